@@ -0,0 +1,33 @@
+package wal
+
+import "errors"
+
+// ErrEmptyBatch is returned by WriteBatch if batch has no records.
+var ErrEmptyBatch = errors.New("write empty batch")
+
+// Batch collects records which are written to wal as a unit,
+// on recovery, either all of them are replayed, or none of them.
+type Batch struct {
+	entries []Entry
+}
+
+// NewBatch returns an empty Batch.
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Add append record to batch, data must not be modified
+// until the batch is written.
+func (b *Batch) Add(index uint64, data []byte) {
+	b.entries = append(b.entries, Entry{Index: index, Data: data})
+}
+
+// Len returns the number of records in batch.
+func (b *Batch) Len() int {
+	return len(b.entries)
+}
+
+// Reset clears batch, so it could be reused.
+func (b *Batch) Reset() {
+	b.entries = b.entries[:0]
+}
@@ -0,0 +1,156 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+func writeBatch(t *testing.T, wal *Wal, first uint64, n int, size int) {
+	batch := NewBatch()
+	for i := 0; i < n; i++ {
+		batch.Add(first+uint64(i), make([]byte, size))
+	}
+	if err := <-wal.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// dropLastCommit remove the commit of the last batch in dir,
+// as if the process crashed before writing it.
+func dropLastCommit(t *testing.T, dir string) {
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, names[len(names)-1])
+	f, err := record.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := int64(-1)
+	err = f.Replay(func(r *record.Record) error {
+		if r.Type == record.RecordCommit {
+			offset = r.Offset
+		}
+		return nil
+	})
+	f.Close()
+	if err != nil || offset < 0 {
+		t.Fatalf("commit not found: %v", err)
+	}
+
+	fd, err := os.OpenFile(path, os.O_RDWR, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if _, err = fd.WriteAt(make([]byte, 32), offset); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func openAndCount(t *testing.T, dir string, opts *Options) (*Wal, uint64) {
+	var count uint64
+	wal, err := OpenWithOptions(dir, 0, func(index uint64, data []byte) error {
+		if index != count {
+			t.Fatalf("index = %d, want %d", index, count)
+		}
+		count++
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wal, count
+}
+
+func TestWriteBatch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err = <-wal.Write(uint64(i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// crosses files.
+	writeBatch(t, wal, 5, 10, 1000)
+	if len(wal.recordFiles) < 3 {
+		t.Errorf("files = %d, want batch crosses files", len(wal.recordFiles))
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count := openAndCount(t, dir, opts)
+	if count != 15 {
+		t.Errorf("count = %d, want 15", count)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteBatch_Empty(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.WriteBatch(NewBatch()); !errors.Is(err, ErrEmptyBatch) {
+		t.Errorf("err = %v, want %v", err, ErrEmptyBatch)
+	}
+}
+
+func TestOpen_UncommittedBatch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeBatch(t, wal, 0, 5, 100)
+	files := len(wal.recordFiles)
+	writeBatch(t, wal, 5, 10, 1000)
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dropLastCommit(t, dir)
+
+	wal, count := openAndCount(t, dir, opts)
+	if count != 5 {
+		t.Errorf("count = %d, want 5", count)
+	}
+	if len(wal.recordFiles) != files {
+		t.Errorf("files = %d, want %d", len(wal.recordFiles), files)
+	}
+	if err = <-wal.Write(5, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count = openAndCount(t, dir, opts)
+	if count != 6 {
+		t.Errorf("count = %d, want 6", count)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteCtx(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = wal.WriteCtx(ctx, 1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.SyncCtx(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the cancelled record is dropped.
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = wal.WriteCtx(cancelled, 2, []byte{0x2}); err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if err = wal.SyncCtx(cancelled); err != context.Canceled {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+	if err = wal.WriteCtx(ctx, 2, []byte{0x2}); err != nil {
+		t.Fatal(err)
+	}
+	if wal.LastIndex() != 2 {
+		t.Errorf("last index = %d, want 2", wal.LastIndex())
+	}
+}
+
+func TestHandle_Cancelled(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	rf, err := createFile(dir, 0, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal := &Wal{
+		walDir:      dir,
+		opts:        opts,
+		recordFiles: []*recordFile{rf},
+	}
+	defer closeAll(wal.recordFiles)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd, ch := genAppend(0, []byte{0x1})
+	cmd.ctx = ctx
+	if wal.handle(&cmd) {
+		t.Errorf("cancelled append requires sync")
+	}
+	if err = <-ch; err != context.Canceled {
+		t.Errorf("err = %v, want %v", err, context.Canceled)
+	}
+	if rf.file.LastIndex()+1 != 0 {
+		t.Errorf("cancelled record is written")
+	}
+	if len(wal.pending) != 0 {
+		t.Errorf("cancelled append is pending")
+	}
+}
@@ -0,0 +1,46 @@
+package wal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDirectIO(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 16 * 1024, DirectIO: true}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Skipf("direct io isn't supported: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 500)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeBatch(t, wal, 100, 10, 1000)
+	if len(wal.recordFiles) < 3 {
+		t.Errorf("files = %d, want rotated", len(wal.recordFiles))
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count := openAndCount(t, dir, opts)
+	if count != 110 {
+		t.Errorf("count = %d, want 110", count)
+	}
+	if err = <-wal.Write(110, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count = openAndCount(t, dir, nil)
+	defer wal.Close()
+	if count != 111 {
+		t.Errorf("count = %d, want 111", count)
+	}
+}
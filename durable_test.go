@@ -0,0 +1,143 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWaitDurable(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncManual})
+	defer os.RemoveAll(dir)
+
+	if wal.LastIndex() != 0 || wal.LastSyncedIndex() != 0 {
+		t.Fatalf("last = %d, synced = %d, want 0, 0", wal.LastIndex(), wal.LastSyncedIndex())
+	}
+
+	for i := 1; i <= 3; i++ {
+		if err := <-wal.Write(uint64(i), []byte{0x1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if wal.LastIndex() != 3 || wal.LastSyncedIndex() != 0 {
+		t.Fatalf("last = %d, synced = %d, want 3, 0", wal.LastIndex(), wal.LastSyncedIndex())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := wal.WaitDurable(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- wal.WaitDurable(context.Background(), 3)
+	}()
+	if err := <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+	if wal.LastSyncedIndex() != 3 {
+		t.Errorf("synced = %d, want 3", wal.LastSyncedIndex())
+	}
+
+	go func() {
+		result <- wal.WaitDurable(context.Background(), 4)
+	}()
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; !errors.Is(err, ErrWalClosed) {
+		t.Errorf("err = %v, want %v", err, ErrWalClosed)
+	}
+
+	wal, err := Open(dir, 1, emptyConsumer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if wal.LastIndex() != 3 || wal.LastSyncedIndex() != 3 {
+		t.Errorf("last = %d, synced = %d, want 3, 3", wal.LastIndex(), wal.LastSyncedIndex())
+	}
+
+	if err = <-wal.TruncateAfter(1); err != nil {
+		t.Fatal(err)
+	}
+	if wal.LastIndex() != 1 || wal.LastSyncedIndex() != 1 {
+		t.Errorf("last = %d, synced = %d, want 1, 1", wal.LastIndex(), wal.LastSyncedIndex())
+	}
+}
+
+func TestWaitDurable_BatchRotated(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := CreateWithOptions(dir, 1, &Options{SegmentSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the files are synced by rotation before the commit is written.
+	writeBatch(t, wal, 2, 20, 500)
+	if len(wal.recordFiles) < 2 {
+		t.Fatalf("files = %d, want batch crosses files", len(wal.recordFiles))
+	}
+	if wal.LastIndex() != 21 || wal.LastSyncedIndex() != 1 {
+		t.Errorf("last = %d, synced = %d, want 21, 1", wal.LastIndex(), wal.LastSyncedIndex())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err = wal.WaitDurable(ctx, 5); err != context.DeadlineExceeded {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if wal.LastSyncedIndex() != 21 {
+		t.Errorf("synced = %d, want 21", wal.LastSyncedIndex())
+	}
+}
+
+func TestTailDurable(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncManual})
+	defer os.RemoveAll(dir)
+	defer wal.Close()
+
+	reader, err := wal.TailDurable(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	next := make(chan bool, 1)
+	go func() {
+		next <- reader.Next()
+	}()
+	select {
+	case <-next:
+		t.Fatal("read the record not synced")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !<-next || reader.Index() != 1 {
+		t.Errorf("want record 1: %v", reader.Err())
+	}
+}
@@ -0,0 +1,145 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+// Entry is a record of wal.
+type Entry struct {
+	Index uint64
+	Data  []byte
+}
+
+// Entries returns records whose index in [lo, hi). They are read from
+// files by another file descriptor, so the writer isn't blocked during
+// reading. It returns ErrIndexOutOfRange if any of them isn't in wal,
+// such as released or not written. The records not synced are read
+// too, but the writer must flush its buffer at first, it waits behind
+// the queued writes, and rewrites the padded last block with DirectIO.
+// Limit hi by LastSyncedIndex+1 to read the durable records only, they
+// are read without disturbing the writer.
+func (wal *Wal) Entries(lo, hi uint64) ([]Entry, error) {
+	if lo >= hi {
+		return nil, nil
+	}
+
+	// make the written records visible, the synced ones are.
+	if hi > wal.LastSyncedIndex()+1 {
+		cmd, ch := genFlush()
+		wal.queue <- cmd
+		if err := <-ch; err != nil {
+			return nil, err
+		}
+	}
+
+	files := wal.snapshot()
+	k := len(files) - 1
+	for k >= 0 && files[k].index > lo {
+		k--
+	}
+	if k < 0 {
+		return nil, fmt.Errorf("%w: entries from %d, first index %d",
+			ErrIndexOutOfRange, lo, files[0].index)
+	}
+
+	size := uint64(maxBatchSize)
+	if hi-lo < size {
+		size = hi - lo
+	}
+	entries := make([]Entry, 0, size)
+	assembler := record.NewAssembler(func(index uint64, data []byte) error {
+		if index >= hi {
+			return errStopScan
+		}
+		if index >= lo {
+			entries = append(entries, Entry{Index: index, Data: data})
+		}
+		return nil
+	})
+	for i := k; i < len(files); i++ {
+		var offset int64
+		if i == k && lo > files[i].index {
+			offset, _ = files[i].file.Lookup(lo)
+		}
+		err := readFile(files[i].filename, offset, assembler.Visit)
+		if err == errStopScan {
+			break
+		} else if record.IsCorrupted(err) && i == len(files)-1 {
+			// the tail is being written.
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if uint64(len(entries)) != hi-lo {
+		return nil, fmt.Errorf("%w: entries [%d, %d), found %d",
+			ErrIndexOutOfRange, lo, hi, len(entries))
+	}
+	return entries, nil
+}
+
+// Entry returns data of record at index, see Entries.
+func (wal *Wal) Entry(index uint64) ([]byte, error) {
+	entries, err := wal.Entries(index, index+1)
+	if err != nil {
+		return nil, err
+	}
+	return entries[0].Data, nil
+}
+
+// fileInfo describes a file of wal, used by readers.
+type fileInfo struct {
+	filename string
+	seq      uint64
+	index    uint64
+	file     *record.File
+}
+
+// snapshot returns files of wal at now.
+func (wal *Wal) snapshot() []fileInfo {
+	wal.mu.RLock()
+	defer wal.mu.RUnlock()
+
+	files := make([]fileInfo, 0, len(wal.recordFiles))
+	for _, rf := range wal.recordFiles {
+		files = append(files, fileInfo{
+			filename: rf.filename,
+			seq:      rf.seq,
+			index:    rf.index,
+			file:     rf.file,
+		})
+	}
+	return files
+}
+
+// readFile read records of file from offset by record.Reader,
+// zero offset means the first record.
+func readFile(filename string, offset int64, visitor record.Visitor) error {
+	reader, err := record.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if offset > 0 {
+		if err = reader.SeekTo(offset); err != nil {
+			return err
+		}
+	}
+
+	var r record.Record
+	for {
+		if err = reader.Next(&r); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = visitor(&r); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestEntries writes records in [0, 30) to three files, the
+// first two of them have 13 records.
+func TestEntries(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	for i := 0; i < 20; i++ {
+		data := make([]byte, 300)
+		data[0] = byte(i)
+		if err = <-wal.Write(uint64(i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batch := NewBatch()
+	for i := 20; i < 30; i++ {
+		data := make([]byte, 300)
+		data[0] = byte(i)
+		batch.Add(uint64(i), data)
+	}
+	if err = <-wal.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		lo, hi uint64
+		werr   error
+	}{
+		{0, 30, nil},
+		{5, 6, nil},
+		{12, 14, nil},
+		{13, 26, nil},
+		{25, 28, nil},
+		{10, 10, nil},
+		{20, 31, ErrIndexOutOfRange},
+		{30, 31, ErrIndexOutOfRange},
+	}
+	for i, test := range tests {
+		entries, err := wal.Entries(test.lo, test.hi)
+		if !errors.Is(err, test.werr) {
+			t.Fatalf("#%d: err = %v, want %v", i, err, test.werr)
+		}
+		if err != nil {
+			continue
+		}
+		if uint64(len(entries)) != test.hi-test.lo {
+			t.Fatalf("#%d: len = %d, want %d", i, len(entries), test.hi-test.lo)
+		}
+		for j, entry := range entries {
+			want := test.lo + uint64(j)
+			if entry.Index != want || entry.Data[0] != byte(want) {
+				t.Fatalf("#%d: entry = %d, want %d", i, entry.Index, want)
+			}
+		}
+	}
+
+	data, err := wal.Entry(17)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0] != 17 {
+		t.Errorf("data = %d, want 17", data[0])
+	}
+
+	if err = <-wal.ReleaseBefore(13); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = wal.Entry(12); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("err = %v, want %v", err, ErrIndexOutOfRange)
+	}
+}
+
+func TestEntries_Concurrent(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			if err := <-wal.Write(uint64(i), make([]byte, 100)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			if _, err = wal.Entries(0, 200); err != nil {
+				t.Fatal(err)
+			}
+			return
+		default:
+		}
+		if _, err = wal.Entries(0, 1); err != nil && !errors.Is(err, ErrIndexOutOfRange) {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEntries_Synced(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	for i := 1; i <= 5; i++ {
+		if err = <-wal.Write(uint64(i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	// record 6 is in the buffer of writer.
+	if err = <-wal.Write(6, []byte{0x6}); err != nil {
+		t.Fatal(err)
+	}
+
+	lastFlushed := func() uint64 {
+		reader, err := NewReader(dir, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		var last uint64
+		for reader.Next() {
+			last = reader.Index()
+		}
+		return last
+	}
+
+	entries, err := wal.Entries(1, wal.LastSyncedIndex()+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[4].Index != 5 {
+		t.Errorf("entries = %v, want [1, 5]", entries)
+	}
+	if last := lastFlushed(); last != 5 {
+		t.Errorf("last flushed = %d, want 5, the buffer isn't flushed", last)
+	}
+
+	if entries, err = wal.Entries(1, 7); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 6 {
+		t.Errorf("entries = %v, want [1, 6]", entries)
+	}
+	if last := lastFlushed(); last != 6 {
+		t.Errorf("last flushed = %d, want 6", last)
+	}
+}
@@ -0,0 +1,54 @@
+package wal
+
+import "errors"
+
+// ErrWalFailed is returned by all commands after the wal meets an
+// I/O error, such as a failed write or sync. Once failed, the state
+// of written records is unknown, it's unsafe to retry or to continue
+// appending. The wal must be closed, and reopened by Open, which
+// replays the records on stable storage only.
+var ErrWalFailed = errors.New("wal failed")
+
+// failedError wraps the I/O error failed the wal, it's matched by
+// ErrWalFailed with errors.Is, and unwraps to the cause.
+type failedError struct {
+	cause error
+}
+
+func (e *failedError) Error() string {
+	return ErrWalFailed.Error() + ": " + e.cause.Error()
+}
+
+func (e *failedError) Is(target error) bool {
+	return target == ErrWalFailed
+}
+
+func (e *failedError) Unwrap() error {
+	return e.cause
+}
+
+// fail make wal failed by err if it isn't, and returns the failure,
+// all waiters of durability and tailing readers are waked up. Only
+// called by service.
+func (wal *Wal) fail(err error) error {
+	wal.notifyMu.Lock()
+	defer wal.notifyMu.Unlock()
+	if wal.failure != nil {
+		return wal.failure
+	}
+
+	wal.opts.Logger.Errorf("wal failed: %v", err)
+	wal.failure = &failedError{cause: err}
+	close(wal.durableCh)
+	wal.durableCh = make(chan struct{})
+	close(wal.notifyCh)
+	wal.notifyCh = make(chan struct{})
+	return wal.failure
+}
+
+// Err returns nil if wal works, or the failure wraps ErrWalFailed.
+func (wal *Wal) Err() error {
+	wal.notifyMu.Lock()
+	defer wal.notifyMu.Unlock()
+	return wal.failure
+}
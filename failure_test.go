@@ -0,0 +1,169 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+func TestFailure_Sticky(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	rf, err := createFile(dir, 0, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal := &Wal{
+		walDir:      dir,
+		opts:        opts,
+		recordFiles: []*recordFile{rf},
+		notifyCh:    make(chan struct{}),
+		durableCh:   make(chan struct{}),
+	}
+
+	// the records couldn't be persisted any more.
+	if err = rf.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	queue := make(chan command, 10)
+	results := make([]<-chan error, 0)
+	for i := 1; i <= 2; i++ {
+		cmd, ch := genAppend(uint64(i), []byte{byte(i)})
+		queue <- cmd
+		results = append(results, ch)
+	}
+	cmd, ch := genSync()
+	queue <- cmd
+	results = append(results, ch)
+	wal.handleBatch(<-queue, queue)
+
+	for i, ch := range results {
+		if err := <-ch; !errors.Is(err, ErrWalFailed) {
+			t.Errorf("#%d: err = %v, want %v", i, err, ErrWalFailed)
+		}
+	}
+	if !errors.Is(wal.Err(), ErrWalFailed) {
+		t.Errorf("err = %v, want %v", wal.Err(), ErrWalFailed)
+	}
+
+	// the following commands fail too, even if they don't do I/O.
+	cmd, ch = genAppend(3, []byte{0x3})
+	queue <- cmd
+	wal.handleBatch(<-queue, queue)
+	if err = <-ch; !errors.Is(err, ErrWalFailed) {
+		t.Errorf("err = %v, want %v", err, ErrWalFailed)
+	}
+	if err = wal.Err(); errors.Unwrap(err) == nil {
+		t.Errorf("failure should wrap the cause")
+	}
+}
+
+func TestFailure_NotIO(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.Write(2, []byte{0x2}); !errors.Is(err, ErrIndexNotContinuous) {
+		t.Fatalf("err = %v, want %v", err, ErrIndexNotContinuous)
+	}
+	if err = <-wal.TruncateAfter(100); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Err(); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestFailure_Reopen(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the service is idle, the file is closed behind it.
+	if err = wal.back().file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(2, []byte{0x2}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Sync(); !errors.Is(err, ErrWalFailed) {
+		t.Fatalf("err = %v, want %v", err, ErrWalFailed)
+	}
+	if err = <-wal.Write(3, []byte{0x3}); !errors.Is(err, ErrWalFailed) {
+		t.Errorf("err = %v, want %v", err, ErrWalFailed)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = wal.WaitDurable(ctx, 2); !errors.Is(err, ErrWalFailed) {
+		t.Errorf("err = %v, want %v", err, ErrWalFailed)
+	}
+	if err = wal.Close(); !errors.Is(err, ErrWalFailed) {
+		t.Errorf("err = %v, want %v", err, ErrWalFailed)
+	}
+
+	// reopen recovers the synced records.
+	var indexes []uint64
+	wal, err = Open(dir, 1, func(index uint64, data []byte) error {
+		indexes = append(indexes, index)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if len(indexes) != 1 || indexes[0] != 1 {
+		t.Errorf("replayed %v, want [1]", indexes)
+	}
+	if err = <-wal.Write(2, []byte{0x2}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailure_EmptyRecord(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.Write(1, nil); !errors.Is(err, record.ErrEmptyRecord) {
+		t.Fatalf("err = %v, want %v", err, record.ErrEmptyRecord)
+	}
+	b := NewBatch()
+	b.Add(1, []byte{0x1})
+	b.Add(2, nil)
+	if err = <-wal.WriteBatch(b); !errors.Is(err, record.ErrEmptyRecord) {
+		t.Fatalf("err = %v, want %v", err, record.ErrEmptyRecord)
+	}
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+}
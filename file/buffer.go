@@ -4,7 +4,8 @@ import (
 	"io"
 )
 
-const bufferSize = 4 * 1024 // default buffer size 4K
+// DefaultBufferSize is the default size of Buffer, 4K.
+const DefaultBufferSize = 4 * 1024
 
 // Buffer give buffer io support for io.ReadWriter.
 // 	file, _ := os.Create(filename)
@@ -19,6 +20,7 @@ const bufferSize = 4 * 1024 // default buffer size 4K
 //  buffer.Read(buffer)
 type Buffer struct {
 	file         io.ReadWriter
+	size         int
 	inboundMark  int
 	outboundMark int
 	outboundSize int
@@ -29,10 +31,19 @@ type Buffer struct {
 // BufferCreate buffered exists io.ReadWriter.
 // Notice: buffer will no flush when exit, caller must ensure flush it.
 func BufferCreate(file io.ReadWriter) *Buffer {
+	return BufferCreateSize(file, DefaultBufferSize)
+}
+
+// BufferCreateSize same as BufferCreate, but with given buffer size.
+func BufferCreateSize(file io.ReadWriter, size int) *Buffer {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
 	return &Buffer{
 		file:         file,
-		inbound:      make([]byte, bufferSize),
-		outbound:     make([]byte, bufferSize),
+		size:         size,
+		inbound:      make([]byte, size),
+		outbound:     make([]byte, size),
 		inboundMark:  0,
 		outboundMark: 0,
 		outboundSize: 0,
@@ -43,7 +54,7 @@ func BufferCreate(file io.ReadWriter) *Buffer {
 func (buf *Buffer) Write(bytes []byte) error {
 	var err error
 	for err == nil && len(bytes) > 0 {
-		if buf.inboundMark != 0 || len(bytes) < bufferSize {
+		if buf.inboundMark != 0 || len(bytes) < buf.size {
 			wtn := buf.fillInbound(bytes)
 			bytes = bytes[wtn:]
 		} else {
@@ -78,23 +89,33 @@ func (buf *Buffer) Flush() error {
 }
 
 // Readn reads up to length bytes from the File.
-// It returns any error encountered. At end of file, Read returns io.EOF.
+// It returns any error encountered. At end of file, Read returns io.EOF
+// if no bytes were read, or io.ErrUnexpectedEOF if part of bytes were read.
 func (buf *Buffer) Readn(bytes []byte, length int) error {
 	// push any data to io, ensure consistency.
 	buf.Flush()
 
 	var err error
+	var readn int
 	for err == nil && length > 0 {
 		if !buf.isOutboundEmpty() {
 			rdn := buf.releaseOutbound(bytes, length)
 			bytes = bytes[rdn:]
 			length -= rdn
-		} else if length >= bufferSize {
-			return buf.readDirectly(bytes, length)
+			readn += rdn
+		} else if length >= buf.size {
+			var rdn int
+			rdn, err = buf.readDirectly(bytes, length)
+			readn += rdn
+			break
 		} else if buf.isOutboundEmpty() {
 			err = buf.loadOutbound()
 		}
 	}
+
+	if err == io.EOF && readn > 0 {
+		err = io.ErrUnexpectedEOF
+	}
 	return err
 }
 
@@ -110,11 +131,11 @@ func (buf *Buffer) Reset() {
 }
 
 func (buf *Buffer) inboundLeft() int {
-	return bufferSize - buf.inboundMark
+	return buf.size - buf.inboundMark
 }
 
 func (buf *Buffer) isInboundFull() bool {
-	return bufferSize == buf.inboundMark
+	return buf.size == buf.inboundMark
 }
 
 func (buf *Buffer) outboundLeft() int {
@@ -133,9 +154,8 @@ func (buf *Buffer) writeDirectly(bytes []byte) error {
 	return err
 }
 
-func (buf *Buffer) readDirectly(bytes []byte, length int) error {
-	_, err := buf.file.Read(bytes[:length])
-	return err
+func (buf *Buffer) readDirectly(bytes []byte, length int) (int, error) {
+	return io.ReadFull(buf.file, bytes[:length])
 }
 
 //
@@ -160,6 +180,9 @@ func (buf *Buffer) loadOutbound() error {
 	n, err := buf.file.Read(buf.outbound)
 	buf.outboundSize = n
 	buf.outboundMark = 0
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
 	return err
 }
 
@@ -114,3 +114,24 @@ test:
 		}
 	}
 }
+
+func TestBuffer_ReadUnexpectedEOF(t *testing.T) {
+	tests := []struct {
+		size, read int
+		werr       error
+	}{
+		{0, 10, io.EOF},
+		{5, 10, io.ErrUnexpectedEOF},
+		{10, 10, nil},
+		{0, DefaultBufferSize * 2, io.EOF},
+		{DefaultBufferSize, DefaultBufferSize * 2, io.ErrUnexpectedEOF},
+		{DefaultBufferSize * 2, DefaultBufferSize * 2, nil},
+	}
+
+	for i, test := range tests {
+		buf := BufferCreate(MakeMemoryFile(randBytes(test.size)))
+		if err := buf.Read(make([]byte, test.read)); err != test.werr {
+			t.Errorf("#%d: err = %v, want %v", i, err, test.werr)
+		}
+	}
+}
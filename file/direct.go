@@ -0,0 +1,18 @@
+package file
+
+import "unsafe"
+
+// DirectAlignment is the alignment of offset, length and memory of
+// the I/O on file opened by OpenDirect.
+const DirectAlignment = 4096
+
+// AlignedBlock returns a buffer of size bytes, its memory is aligned
+// to DirectAlignment, size must be multiple of DirectAlignment.
+func AlignedBlock(size int) []byte {
+	block := make([]byte, size+DirectAlignment)
+	offset := 0
+	if rem := int(uintptr(unsafe.Pointer(&block[0])) & (DirectAlignment - 1)); rem != 0 {
+		offset = DirectAlignment - rem
+	}
+	return block[offset : offset+size : offset+size]
+}
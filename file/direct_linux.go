@@ -0,0 +1,13 @@
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenDirect open file name with O_DIRECT, so I/O on it bypasses page
+// cache, the offset, length and memory of I/O must be aligned to
+// DirectAlignment, see AlignedBlock.
+func OpenDirect(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag|syscall.O_DIRECT, perm)
+}
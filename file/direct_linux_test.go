@@ -0,0 +1,43 @@
+package file
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"unsafe"
+)
+
+func TestAlignedBlock(t *testing.T) {
+	for i := 1; i <= 4; i++ {
+		block := AlignedBlock(i * DirectAlignment)
+		if len(block) != i*DirectAlignment || cap(block) != len(block) {
+			t.Errorf("len = %d, cap = %d, want %d", len(block), cap(block), i*DirectAlignment)
+		}
+		if uintptr(unsafe.Pointer(&block[0]))%DirectAlignment != 0 {
+			t.Errorf("block isn't aligned")
+		}
+	}
+}
+
+func TestOpenDirect(t *testing.T) {
+	f, err := OpenDirect("/tmp/xxxx_direct", os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		t.Skipf("direct io isn't supported: %v", err)
+	}
+	defer os.Remove("/tmp/xxxx_direct")
+	defer f.Close()
+
+	block := AlignedBlock(DirectAlignment)
+	copy(block, "direct io")
+	if _, err = f.WriteAt(block, DirectAlignment); err != nil {
+		t.Fatal(err)
+	}
+
+	read := AlignedBlock(DirectAlignment)
+	if _, err = f.ReadAt(read, DirectAlignment); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(read, block) {
+		t.Errorf("read block isn't same as written")
+	}
+}
@@ -0,0 +1,11 @@
+package file
+
+import (
+	"errors"
+	"os"
+)
+
+// OpenDirect isn't supported on windows.
+func OpenDirect(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, errors.New("direct io isn't supported")
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// zeroFillChunk is the size of zeros written once by zeroFill.
+const zeroFillChunk = 64 * 1024
+
+// zeroFill allocate space of f up to size by writing zeros after the
+// end of f, it works on any file system.
+func zeroFill(f *os.File, size int64) error {
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	zeros := make([]byte, zeroFillChunk)
+	for offset := stat.Size(); offset < size; offset += zeroFillChunk {
+		n := size - offset
+		if n > zeroFillChunk {
+			n = zeroFillChunk
+		}
+		if _, err = f.WriteAt(zeros[:n], offset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fallocate allocate disk space of f, it's replaced by tests.
+var fallocate = func(f *os.File, size int64) error {
+	return syscall.Fallocate(int(f.Fd()), 0, 0, size)
+}
+
+// Preallocate allocate disk space of f up to size, and extend f
+// to size if it's smaller, the space allocated reads as zeros. It
+// uses fallocate, and falls back to writing zeros if file system
+// doesn't support it, so the later writes needn't allocate blocks.
+func Preallocate(f *os.File, size int64) error {
+	err := fallocate(f, size)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return zeroFill(f, size)
+	}
+	return err
+}
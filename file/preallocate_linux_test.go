@@ -0,0 +1,78 @@
+package file
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// testPreallocate preallocate a file in dir, and check that the space
+// is allocated and the content is kept.
+func testPreallocate(t *testing.T, dir string) {
+	if _, err := os.Stat(dir); err != nil {
+		t.Logf("skip %s: %v", dir, err)
+		return
+	}
+	filename := filepath.Join(dir, "xxxx_preallocate")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	defer f.Close()
+
+	content := []byte("content before preallocated space")
+	if _, err = f.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	const size = 1024*1024 + 100
+	if err = Preallocate(f, size); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != size {
+		t.Errorf("%s: size = %d, want %d", dir, stat.Size(), size)
+	}
+	if blocks := stat.Sys().(*syscall.Stat_t).Blocks; blocks*512 < size {
+		t.Errorf("%s: %d blocks allocated, want not sparse", dir, blocks)
+	}
+
+	buf := make([]byte, size)
+	if _, err = f.ReadAt(buf, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf[:len(content)], content) {
+		t.Errorf("%s: content = %q, want %q", dir, buf[:len(content)], content)
+	}
+	for _, b := range buf[len(content):] {
+		if b != 0 {
+			t.Fatalf("%s: preallocated space should be zeros", dir)
+		}
+	}
+}
+
+func TestPreallocate(t *testing.T) {
+	// ext4 or others on /tmp, tmpfs on /dev/shm.
+	for _, dir := range []string{"/tmp", "/dev/shm"} {
+		testPreallocate(t, dir)
+	}
+}
+
+func TestPreallocate_ZeroFill(t *testing.T) {
+	defer func(saved func(*os.File, int64) error) {
+		fallocate = saved
+	}(fallocate)
+	fallocate = func(f *os.File, size int64) error {
+		return syscall.EOPNOTSUPP
+	}
+
+	for _, dir := range []string{"/tmp", "/dev/shm"} {
+		testPreallocate(t, dir)
+	}
+}
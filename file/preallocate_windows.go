@@ -0,0 +1,13 @@
+package file
+
+import "os"
+
+// Preallocate extend f to size if it's smaller, the space extended
+// reads as zeros. The clusters of it are allocated by file system.
+func Preallocate(f *os.File, size int64) error {
+	stat, err := f.Stat()
+	if err != nil || stat.Size() >= size {
+		return err
+	}
+	return f.Truncate(size)
+}
@@ -0,0 +1,17 @@
+package file
+
+import "os"
+
+// SyncDir sync directory dir, so the files created, renamed or
+// removed in it are durable.
+func SyncDir(dir string) error {
+	fd, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	if err = fd.Sync(); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
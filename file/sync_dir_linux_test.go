@@ -0,0 +1,20 @@
+package file
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSyncDir(t *testing.T) {
+	if err := os.MkdirAll("/tmp/xxxx_dir", 0777); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("/tmp/xxxx_dir")
+
+	if err := SyncDir("/tmp/xxxx_dir"); err != nil {
+		t.Error(err)
+	}
+	if err := SyncDir("/tmp/xxxx_dir/not_exists"); !os.IsNotExist(err) {
+		t.Errorf("err = %v, want not exists", err)
+	}
+}
@@ -0,0 +1,7 @@
+package file
+
+// SyncDir does nothing, directories couldn't be synced on windows,
+// the changes of them are durable once the files are synced.
+func SyncDir(dir string) error {
+	return nil
+}
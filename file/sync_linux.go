@@ -0,0 +1,15 @@
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// syncFileRangeWrite is SYNC_FILE_RANGE_WRITE of sync_file_range(2).
+const syncFileRangeWrite = 0x2
+
+// Fdatasync flush data of f to disk, the metadata of f is flushed
+// only if it's required to read the data, such as size of f.
+func Fdatasync(f *os.File) error {
+	return syscall.Fdatasync(int(f.Fd()))
+}
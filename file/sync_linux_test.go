@@ -0,0 +1,35 @@
+package file
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFdatasync(t *testing.T) {
+	f, err := os.Create("/tmp/xxxx_sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove("/tmp/xxxx_sync")
+
+	if err = Preallocate(f, 64*1024); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteAt([]byte("records"), 100); err != nil {
+		t.Fatal(err)
+	}
+	if err = StartWriteback(f, 0, 4096); err != nil {
+		t.Error(err)
+	}
+	if err = Fdatasync(f); err != nil {
+		t.Error(err)
+	}
+
+	f.Close()
+	if err = Fdatasync(f); err == nil {
+		t.Errorf("sync closed file must failed")
+	}
+	if err = StartWriteback(f, 0, 4096); err == nil {
+		t.Errorf("writeback closed file must failed")
+	}
+}
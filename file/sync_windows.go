@@ -0,0 +1,14 @@
+package file
+
+import "os"
+
+// Fdatasync flush data and metadata of f to disk, there no
+// cheaper way on windows.
+func Fdatasync(f *os.File) error {
+	return f.Sync()
+}
+
+// StartWriteback does nothing on windows.
+func StartWriteback(f *os.File, offset, n int64) error {
+	return nil
+}
@@ -0,0 +1,15 @@
+// +build linux,!arm
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// StartWriteback start writeback of the dirty pages of f in range
+// [offset, offset+n) in background, it doesn't wait for them and
+// makes nothing durable, it only makes the following sync faster.
+func StartWriteback(f *os.File, offset, n int64) error {
+	return syscall.SyncFileRange(int(f.Fd()), offset, n, syncFileRangeWrite)
+}
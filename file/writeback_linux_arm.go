@@ -0,0 +1,18 @@
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// StartWriteback same as the one on other linux, arm has no
+// sync_file_range but arm_sync_file_range, which takes flags
+// before the 64-bit offset and n.
+func StartWriteback(f *os.File, offset, n int64) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_ARM_SYNC_FILE_RANGE, f.Fd(),
+		syncFileRangeWrite, uintptr(offset), uintptr(offset>>32), uintptr(n), uintptr(n>>32))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
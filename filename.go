@@ -10,16 +10,24 @@ import (
 )
 
 var (
-	errBadWalName   = errors.New("bad wal name")
-	errFileNotFound = errors.New("file not found")
+	// ErrBadWalName means the name isn't a name of wal file.
+	ErrBadWalName = errors.New("bad wal name")
+	// ErrFileNotFound means the wal file contains the required
+	// records isn't found, such as released or never created.
+	ErrFileNotFound = errors.New("file not found")
+	// ErrHeaderMismatch means the header of wal file doesn't match
+	// with the sequence and index in its name.
+	ErrHeaderMismatch = errors.New("wal header mismatch with filename")
 )
 
 func parseWalName(str string) (seq, index uint64, err error) {
 	if !strings.HasSuffix(str, ".wal") {
-		return 0, 0, errBadWalName
+		return 0, 0, fmt.Errorf("%w: %s", ErrBadWalName, str)
 	}
-	_, err = fmt.Sscanf(str, "%016x-%016x.wal", &seq, &index)
-	return seq, index, err
+	if _, err = fmt.Sscanf(str, "%016x-%016x.wal", &seq, &index); err != nil {
+		return 0, 0, fmt.Errorf("%w: %s: %v", ErrBadWalName, str, err)
+	}
+	return seq, index, nil
 }
 
 func mustParseWalName(str string) (uint64, uint64) {
@@ -34,11 +42,11 @@ func walName(seq, index uint64) string {
 	return fmt.Sprintf("%016x-%016x.wal", seq, index)
 }
 
-func filterWalFiles(names []string) []string {
+func filterWalFiles(names []string, logger Logger) []string {
 	result := make([]string, 0)
 	for i := 0; i < len(names); i++ {
 		if _, _, err := parseWalName(names[i]); err != nil {
-			log.Debugf("skip bad wal name: %s", names[i])
+			logger.Debugf("skip bad wal name: %s", names[i])
 			continue
 		}
 		result = append(result, names[i])
@@ -46,24 +54,26 @@ func filterWalFiles(names []string) []string {
 	return result
 }
 
-func readAllWalNames(dir string) ([]string, error) {
+func readAllWalNames(dir string, logger Logger) ([]string, error) {
 	names, err := file.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	names = filterWalFiles(names)
+	names = filterWalFiles(names, logger)
 	if len(names) == 0 {
-		return nil, errFileNotFound
+		return nil, fmt.Errorf("%s: %w", dir, ErrFileNotFound)
 	}
 	return names, nil
 }
 
+// isValidSequences test whether the sequences of names are continuous,
+// names may not start with sequence zero if the earlier files released.
 func isValidSequences(names []string) bool {
 	var lastSeq uint64
-	for _, name := range names {
+	for i, name := range names {
 		curSeq, _ := mustParseWalName(name)
-		if lastSeq != 0 && lastSeq != curSeq-1 {
+		if i > 0 && lastSeq+1 != curSeq {
 			return false
 		}
 		lastSeq = curSeq
@@ -45,6 +45,24 @@ func TestSearchIndex(t *testing.T) {
 	}
 }
 
+func TestIsValidSequences(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  bool
+	}{
+		{[]string{walName(0, 0), walName(1, 10), walName(2, 20)}, true},
+		{[]string{walName(3, 30), walName(4, 40)}, true},
+		{[]string{walName(0, 0), walName(2, 20)}, false},
+		{[]string{walName(1, 10), walName(3, 30)}, false},
+		{[]string{walName(1, 10), walName(1, 20)}, false},
+	}
+	for i, tt := range tests {
+		if get := isValidSequences(tt.names); get != tt.want {
+			t.Errorf("#%d: valid = %v, want %v", i, get, tt.want)
+		}
+	}
+}
+
 func TestScanWalName(t *testing.T) {
 	tests := []struct {
 		str          string
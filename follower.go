@@ -0,0 +1,114 @@
+package wal
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// pollInterval is the interval to check new records if the
+	// changes of directory couldn't be watched.
+	pollInterval = 100 * time.Millisecond
+	// fallbackInterval is the interval to check new records even
+	// if directory is watched, in case of lost events.
+	fallbackInterval = time.Second
+)
+
+// Follower follows the records appended to wal by another process.
+// It opens wal directory read-only, and watches its changes by inotify
+// on linux, so Next blocks until the next record is written. It falls
+// back to polling if the directory couldn't be watched. Only records
+// with valid checksum are returned, files rotated by writer are
+// followed, and Err returns ErrFileNotFound if the next file is
+// released before reading, or ErrTruncated if the records read are
+// discarded by TruncateAfter.
+type Follower struct {
+	*Reader
+}
+
+// NewFollower returns Follower starts at record with index from.
+func NewFollower(dir string, from uint64) (*Follower, error) {
+	r, err := NewReader(dir, from)
+	if err != nil {
+		return nil, err
+	}
+	r.notifier = newDirWatcher(dir)
+	return &Follower{Reader: r}, nil
+}
+
+// dirWatcher notifies followers when files of directory changed.
+type dirWatcher struct {
+	mu       sync.Mutex // guards notifyCh
+	notifyCh chan struct{}
+	checked  chan struct{} // notifyCh when truncation is checked, used by reader
+	stop     chan struct{}
+	watcher  io.Closer
+}
+
+func newDirWatcher(dir string) *dirWatcher {
+	w := &dirWatcher{
+		notifyCh: make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	interval := fallbackInterval
+	watcher, err := watchDir(dir, w.notify)
+	if err != nil {
+		interval = pollInterval
+	} else {
+		w.watcher = watcher
+	}
+	go w.poll(interval)
+	return w
+}
+
+func (w *dirWatcher) poll(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.notify()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+func (w *dirWatcher) notify() {
+	w.mu.Lock()
+	close(w.notifyCh)
+	w.notifyCh = make(chan struct{})
+	w.mu.Unlock()
+}
+
+func (w *dirWatcher) changed() (<-chan struct{}, <-chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.notifyCh, nil
+}
+
+func (w *dirWatcher) failed() error {
+	return nil
+}
+
+// truncated test whether the records read by r are overwritten, it's
+// checked only once after the files changed.
+func (w *dirWatcher) truncated(r *Reader) (bool, error) {
+	w.mu.Lock()
+	ch := w.notifyCh
+	w.mu.Unlock()
+	if ch == w.checked {
+		return false, nil
+	}
+	w.checked = ch
+	return r.file.Stale()
+}
+
+func (w *dirWatcher) release() {
+	close(w.stop)
+	if w.watcher != nil {
+		w.watcher.Close()
+	}
+}
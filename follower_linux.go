@@ -0,0 +1,37 @@
+package wal
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+const watchMask = syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_TO | syscall.IN_CLOSE_WRITE
+
+// watchDir watches the changes of files in dir by inotify, and calls
+// notify for every batch of events, until the returned io.Closer closed.
+func watchDir(dir string, notify func()) (io.Closer, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = syscall.InotifyAddWatch(fd, dir, watchMask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// the non-blocking fd is managed by runtime poller, so the
+	// blocked Read returns once file is closed.
+	file := os.NewFile(uintptr(fd), dir)
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			if _, err := file.Read(buf); err != nil {
+				return
+			}
+			notify()
+		}
+	}()
+	return file, nil
+}
@@ -0,0 +1,13 @@
+// +build !linux
+
+package wal
+
+import (
+	"errors"
+	"io"
+)
+
+// watchDir isn't supported, followers poll the changes.
+func watchDir(dir string, notify func()) (io.Closer, error) {
+	return nil, errors.New("watch directory not supported")
+}
@@ -0,0 +1,157 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestFollower(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	follower, err := NewFollower(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+
+	total := 100
+	go func() {
+		// records cross files.
+		for i := 0; i < total; i++ {
+			if err := <-wal.Write(uint64(i), make([]byte, 100)); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := <-wal.Sync(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		if !follower.Next() {
+			t.Fatalf("stop at %d: %v", i, follower.Err())
+		}
+		if follower.Index() != uint64(i) {
+			t.Fatalf("index = %d, want %d", follower.Index(), i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > fallbackInterval {
+		t.Logf("follow %d records takes %v, changes aren't watched", total, elapsed)
+	}
+}
+
+func TestFollower_Released(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.Write(0, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	follower, err := NewFollower(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+	if !follower.Next() || follower.Index() != 0 {
+		t.Fatalf("want record 0: %v", follower.Err())
+	}
+
+	// the files after the one read by follower are released.
+	for i := 1; i < 40; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 300)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-wal.ReleaseBefore(39); err != nil {
+		t.Fatal(err)
+	}
+
+	for follower.Next() {
+	}
+	if !errors.Is(follower.Err(), ErrFileNotFound) {
+		t.Errorf("err = %v, want %v", follower.Err(), ErrFileNotFound)
+	}
+}
+
+func TestDirWatcher_Polling(t *testing.T) {
+	// the directory couldn't be watched.
+	w := newDirWatcher("/tmp/wal-not-exists")
+	defer w.release()
+	if w.watcher != nil {
+		t.Fatal("watch a missing directory")
+	}
+
+	changed, _ := w.changed()
+	select {
+	case <-changed:
+	case <-time.After(10 * pollInterval):
+		t.Errorf("not notified by polling")
+	}
+}
+
+func TestFollower_Truncated(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := CreateWithOptions(dir, 0, &Options{SegmentSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	for i := 0; i < 10; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 500)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	follower, err := NewFollower(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer follower.Close()
+	for i := 0; i < 10; i++ {
+		if !follower.Next() || follower.Index() != uint64(i) {
+			t.Fatalf("want record %d, err = %v", i, follower.Err())
+		}
+	}
+
+	if err = <-wal.TruncateAfter(4); err != nil {
+		t.Fatal(err)
+	}
+	for i := 5; i < 20; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 500)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	expectTruncated(t, follower.Reader)
+}
@@ -0,0 +1,67 @@
+package wal
+
+import (
+	"os"
+	"testing"
+)
+
+func TestHandleBatch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	rf, err := createFile(dir, 0, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal := &Wal{
+		walDir:      dir,
+		opts:        opts,
+		recordFiles: []*recordFile{rf},
+		notifyCh:    make(chan struct{}),
+		durableCh:   make(chan struct{}),
+	}
+	defer closeAll(wal.recordFiles)
+
+	queue := make(chan command, 10)
+	results := make([]<-chan error, 0)
+	for i := 0; i < 4; i++ {
+		cmd, ch := genAppend(uint64(i), []byte{byte(i)})
+		queue <- cmd
+		results = append(results, ch)
+		cmd, ch = genSync()
+		queue <- cmd
+		results = append(results, ch)
+	}
+
+	if closed := wal.handleBatch(<-queue, queue); closed {
+		t.Fatalf("queue isn't closed")
+	}
+	if len(queue) != 0 {
+		t.Errorf("all commands in queue should be handled as a batch")
+	}
+	for i, ch := range results {
+		if !isResolved(ch) {
+			t.Errorf("#%d: result should be resolved", i)
+		}
+	}
+	if wal.unsyncedRecords != 0 || len(wal.pending) != 0 {
+		t.Errorf("records should be synced")
+	}
+	if wal.syncs != 1 {
+		t.Errorf("syncs = %d, want 1 for the batch", wal.syncs)
+	}
+
+	cmd, ch := genSync()
+	queue <- cmd
+	close(queue)
+	if closed := wal.handleBatch(<-queue, queue); !closed {
+		t.Errorf("queue is closed")
+	}
+	if !isResolved(ch) {
+		t.Errorf("result should be resolved")
+	}
+	if wal.syncs != 2 {
+		t.Errorf("syncs = %d, want 2", wal.syncs)
+	}
+}
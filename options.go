@@ -0,0 +1,112 @@
+package wal
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/thinkermao/wal-go/file"
+	"github.com/thinkermao/wal-go/record"
+)
+
+const (
+	defaultSegmentSize = 1024 * 1024 * 64 // 64MB
+	defaultDirPerm     = 0766
+	defaultFilePerm    = 0777
+)
+
+// Logger used by wal to log messages, *logrus.Logger and
+// *logrus.Entry satisfy it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Options used by CreateWithOptions and OpenWithOptions to change
+// the default behaviors of wal. Zero fields use default values.
+type Options struct {
+	// SegmentSize is the size preallocated for wal file, wal
+	// rotates to new file when size of records great than it.
+	SegmentSize int64
+	// BufferSize is the size of write buffer.
+	BufferSize int
+	// DirPerm is the permission of wal directory created by Create.
+	DirPerm os.FileMode
+	// FilePerm is the permission of wal files.
+	FilePerm os.FileMode
+	// Logger used to log messages, default is the standard
+	// logger of logrus.
+	Logger Logger
+	// RecoveryMode decides how to deal with corrupted records
+	// found by Open, default is TolerateCorruptedTailRecords.
+	RecoveryMode RecoveryMode
+	// SyncPolicy decides when written records are synced, default
+	// is SyncManual.
+	SyncPolicy SyncPolicy
+	// WritebackBytes starts writeback of records in background once
+	// every WritebackBytes bytes written on linux, so the next sync
+	// has less to flush. It makes nothing durable, default is zero,
+	// which disables it.
+	WritebackBytes int64
+	// DirectIO writes records with O_DIRECT on linux, so they bypass
+	// page cache, every flush is padded to the sector alignment.
+	DirectIO bool
+	// DisablePreallocation creates wal file in rotation, instead of
+	// preallocating it in background once the last file is half full.
+	DisablePreallocation bool
+}
+
+// DefaultOptions returns the options used by Create and Open.
+func DefaultOptions() *Options {
+	return &Options{
+		SegmentSize:  defaultSegmentSize,
+		BufferSize:   file.DefaultBufferSize,
+		DirPerm:      defaultDirPerm,
+		FilePerm:     defaultFilePerm,
+		Logger:       log.StandardLogger(),
+		RecoveryMode: TolerateCorruptedTailRecords,
+	}
+}
+
+// withDefaults returns a copy of opts, which zero fields
+// are filled with default values.
+func (opts *Options) withDefaults() *Options {
+	result := DefaultOptions()
+	if opts == nil {
+		return result
+	}
+	if opts.SegmentSize > 0 {
+		result.SegmentSize = opts.SegmentSize
+	}
+	if opts.BufferSize > 0 {
+		result.BufferSize = opts.BufferSize
+	}
+	if opts.DirPerm != 0 {
+		result.DirPerm = opts.DirPerm
+	}
+	if opts.FilePerm != 0 {
+		result.FilePerm = opts.FilePerm
+	}
+	if opts.Logger != nil {
+		result.Logger = opts.Logger
+	}
+	result.RecoveryMode = opts.RecoveryMode
+	result.SyncPolicy = opts.SyncPolicy.withDefaults()
+	if opts.WritebackBytes > 0 {
+		result.WritebackBytes = opts.WritebackBytes
+	}
+	result.DirectIO = opts.DirectIO
+	result.DisablePreallocation = opts.DisablePreallocation
+	return result
+}
+
+func (opts *Options) recordOptions() *record.Options {
+	return &record.Options{
+		Size:           opts.SegmentSize,
+		BufferSize:     opts.BufferSize,
+		Perm:           opts.FilePerm,
+		WritebackBytes: opts.WritebackBytes,
+		Direct:         opts.DirectIO,
+	}
+}
@@ -0,0 +1,122 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type recordLogger struct {
+	warnings []string
+}
+
+func (l *recordLogger) Debugf(format string, args ...interface{}) {}
+func (l *recordLogger) Infof(format string, args ...interface{})  {}
+func (l *recordLogger) Errorf(format string, args ...interface{}) {}
+func (l *recordLogger) Warnf(format string, args ...interface{}) {
+	l.warnings = append(l.warnings, fmt.Sprintf(format, args...))
+}
+
+func TestOptions_WithDefaults(t *testing.T) {
+	opts := (*Options)(nil).withDefaults()
+	want := DefaultOptions()
+	if opts.SegmentSize != want.SegmentSize || opts.BufferSize != want.BufferSize ||
+		opts.DirPerm != want.DirPerm || opts.FilePerm != want.FilePerm ||
+		opts.Logger != want.Logger || opts.RecoveryMode != want.RecoveryMode {
+		t.Errorf("options = %+v, want %+v", opts, want)
+	}
+
+	opts = (&Options{SegmentSize: 1024, RecoveryMode: PointInTimeRecovery}).withDefaults()
+	if opts.SegmentSize != 1024 || opts.RecoveryMode != PointInTimeRecovery {
+		t.Errorf("options = %+v", opts)
+	}
+	if opts.BufferSize != want.BufferSize {
+		t.Errorf("buffer size = %d, want %d", opts.BufferSize, want.BufferSize)
+	}
+}
+
+func TestCreateWithOptions(t *testing.T) {
+	dir := filepath.Join(createTmpDir(t), "sub")
+	defer os.RemoveAll(filepath.Dir(dir))
+
+	opts := &Options{
+		SegmentSize: 64 * 1024,
+		BufferSize:  512,
+		DirPerm:     0700,
+		FilePerm:    0600,
+	}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 1000)
+	for i := 0; i < 200; i++ {
+		if err = <-wal.Write(uint64(i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0700 {
+		t.Errorf("dir perm = %v, want %v", stat.Mode().Perm(), os.FileMode(0700))
+	}
+
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) < 3 {
+		t.Errorf("files = %d, want rotated by small segment size", len(names))
+	}
+	for _, name := range names {
+		stat, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Size() < opts.SegmentSize || stat.Size() > opts.SegmentSize+2*int64(len(data)) {
+			t.Errorf("%s: size = %d, want about %d", name, stat.Size(), opts.SegmentSize)
+		}
+		if stat.Mode().Perm() != 0600 {
+			t.Errorf("%s: perm = %v, want %v", name, stat.Mode().Perm(), os.FileMode(0600))
+		}
+	}
+
+	count := 0
+	wal, err = OpenWithOptions(dir, 0, func(index uint64, data []byte) error {
+		count++
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 200 {
+		t.Errorf("count = %d, want 200", count)
+	}
+	wal.Close()
+}
+
+func TestOpenWithOptions_Logger(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	offsets := createFileWithRecords(t, dir, 0, 0, 10)
+	corruptFile(t, filepath.Join(dir, walName(0, 0)), offsets[9])
+
+	logger := &recordLogger{}
+	wal, err := OpenWithOptions(dir, 0, emptyConsumer, &Options{Logger: logger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+	if len(logger.warnings) != 1 {
+		t.Errorf("warnings = %v, want one warning", logger.warnings)
+	}
+}
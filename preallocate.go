@@ -0,0 +1,125 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+// preallocatedName is the name of the file preallocated for the
+// next wal file, it's removed by Open if left by crash.
+const preallocatedName = "preallocated.tmp"
+
+// maxSealing is the number of rotated files could wait for sealing.
+const maxSealing = 16
+
+// allocator keeps the next wal file preallocated in background, so
+// rotation only writes header and renames it. The rotated files are
+// sealed in background too. Only called by service.
+type allocator struct {
+	filename string
+	opts     *record.Options
+	logger   Logger
+	disabled bool // files aren't preallocated
+	busy     bool // a file is being preallocated or ready
+	sealing  sync.WaitGroup
+	want     chan struct{}
+	ready    chan error
+	seals    chan *recordFile
+	done     chan struct{}
+}
+
+func newAllocator(dir string, opts *Options) *allocator {
+	a := &allocator{
+		filename: filepath.Join(dir, preallocatedName),
+		opts:     opts.recordOptions(),
+		logger:   opts.Logger,
+		disabled: opts.DisablePreallocation,
+		want:     make(chan struct{}, 1),
+		ready:    make(chan error, 1),
+		seals:    make(chan *recordFile, maxSealing),
+		done:     make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+func (a *allocator) run() {
+	defer close(a.done)
+	for {
+		select {
+		case _, ok := <-a.want:
+			if !ok {
+				return
+			}
+			a.ready <- record.Preallocate(a.filename, a.opts)
+		case rf := <-a.seals:
+			// the sidecar is rebuilt by Open if it's missing.
+			if err := rf.file.Seal(); err != nil {
+				a.logger.Warnf("write index file of %s: %v", rf.filename, err)
+			}
+			a.sealing.Done()
+		}
+	}
+}
+
+// prepare start to preallocate a file if there no one.
+func (a *allocator) prepare() {
+	if !a.disabled && !a.busy {
+		a.busy = true
+		a.want <- struct{}{}
+	}
+}
+
+// seal write the sidecar index file of rf in background, rf must
+// not be written any more.
+func (a *allocator) seal(rf *recordFile) {
+	a.sealing.Add(1)
+	a.seals <- rf
+}
+
+// wait for the files being sealed, it must be called before the
+// sealed files are truncated, removed or closed.
+func (a *allocator) wait() {
+	a.sealing.Wait()
+}
+
+// take returns true if the file is preallocated, it must be renamed
+// before preparing another one. It never waits for preallocating.
+func (a *allocator) take() bool {
+	select {
+	case err := <-a.ready:
+		a.busy = false
+		if err != nil {
+			a.logger.Warnf("preallocate wal file %s: %v", a.filename, err)
+			return false
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// close wait for the preallocating file and the files being sealed,
+// and remove the preallocated file.
+func (a *allocator) close() {
+	a.wait()
+	close(a.want)
+	<-a.done
+	if err := os.Remove(a.filename); err != nil && !os.IsNotExist(err) {
+		a.logger.Warnf("remove preallocated file %s: %v", a.filename, err)
+	}
+}
+
+// nextFile create the file follows the last one, from the preallocated
+// file if it's ready, otherwise a new one is created.
+func (wal *Wal) nextFile() (*recordFile, error) {
+	rf := wal.back()
+	var tmpname string
+	if wal.allocator.take() {
+		tmpname = wal.allocator.filename
+	}
+	return createFileFrom(tmpname, wal.walDir, rf.seq+1, rf.lastIndex+1, wal.opts)
+}
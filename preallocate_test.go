@@ -0,0 +1,174 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+func TestAllocator(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := (&Options{SegmentSize: 4096}).withDefaults()
+	a := newAllocator(dir, opts)
+	a.prepare()
+	for !a.take() {
+		time.Sleep(time.Millisecond)
+	}
+	tmp, err := os.Stat(a.filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rf, err := createFileFrom(a.filename, dir, 1, 10, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.file.Close()
+	stat, err := os.Stat(filepath.Join(dir, walName(1, 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(tmp, stat) || stat.Size() != 4096 {
+		t.Errorf("wal file should be renamed from the preallocated file")
+	}
+	if header := rf.file.Header(); header.Sequence != 1 || header.Index != 10 {
+		t.Errorf("header = %+v, want sequence 1, index 10", header)
+	}
+
+	a.prepare()
+	a.close()
+	if _, err = os.Stat(a.filename); !os.IsNotExist(err) {
+		t.Errorf("preallocated file should be removed, err = %v", err)
+	}
+}
+
+func TestRotatePreallocated(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if i%10 == 0 {
+			// let the next file be preallocated.
+			time.Sleep(time.Millisecond)
+		}
+		if err = <-wal.Write(uint64(i), make([]byte, 200)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, preallocatedName)); !os.IsNotExist(err) {
+		t.Errorf("preallocated file should be removed, err = %v", err)
+	}
+
+	wal, count := openAndCount(t, dir, opts)
+	defer wal.Close()
+	if count != 100 {
+		t.Errorf("count = %d, want 100", count)
+	}
+	if len(wal.recordFiles) < 3 {
+		t.Errorf("files = %d, want rotated", len(wal.recordFiles))
+	}
+}
+
+func TestPreallocate_Lazy(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := CreateWithOptions(dir, 0, &Options{SegmentSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	tmpname := filepath.Join(dir, preallocatedName)
+
+	if err = <-wal.Write(0, make([]byte, 100)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if _, err = os.Stat(tmpname); !os.IsNotExist(err) {
+		t.Errorf("file is preallocated before half full, err = %v", err)
+	}
+
+	// half full.
+	if err = <-wal.Write(1, make([]byte, 2048)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		if _, err = os.Stat(tmpname); err == nil {
+			break
+		} else if i == 100 {
+			t.Fatalf("file isn't preallocated after half full, err = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPreallocate_Disabled(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096, DisablePreallocation: true}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 200)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = os.Stat(filepath.Join(dir, preallocatedName)); !os.IsNotExist(err) {
+			t.Fatalf("file is preallocated, err = %v", err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count := openAndCount(t, dir, opts)
+	defer wal.Close()
+	if count != 100 || len(wal.recordFiles) < 3 {
+		t.Errorf("count = %d, files = %d, want 100 records rotated", count, len(wal.recordFiles))
+	}
+}
+
+func TestRotate_SealBackground(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := CreateWithOptions(dir, 0, &Options{SegmentSize: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	for i := 0; i < 30; i++ {
+		if err = <-wal.Write(uint64(i), make([]byte, 200)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rotated files are sealed before they are released.
+	wal.allocator.wait()
+	for _, rf := range wal.recordFiles[:len(wal.recordFiles)-1] {
+		if _, err = os.Stat(rf.filename + record.IndexFileSuffix); err != nil {
+			t.Errorf("file %s isn't sealed: %v", rf.filename, err)
+		}
+	}
+	if err = <-wal.ReleaseBefore(20); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.TruncateAfter(25); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,410 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+// ErrReaderClosed is returned by Reader after it is closed.
+var ErrReaderClosed = errors.New("reader is closed")
+
+// ErrTruncated is returned by Reader if the records it has read are
+// discarded by TruncateAfter, the records following them couldn't be
+// found. A new reader should be created at the index wanted.
+var ErrTruncated = errors.New("records read are truncated")
+
+// Reader iterates records of wal in order of index. It opens files
+// read-only and takes no lock, so it works even while wal is written
+// by others, only the records flushed by writer are visible.
+//
+//	reader, err := wal.NewReader(dir, from)
+//	for reader.Next() {
+//		consume(reader.Index(), reader.Data())
+//	}
+//	err = reader.Err()
+//	reader.Close()
+type Reader struct {
+	mu        sync.Mutex // held during reading, so Close could be called by others
+	dir       string
+	from      uint64
+	seq       uint64 // sequence of current file
+	file      *record.Reader
+	assembler *record.Assembler
+	queue     []Entry // assembled records
+	readNext  uint64  // index follows the records read, zero if none
+	entry     Entry
+	err       error
+
+	// used by tailing reader only.
+	notifier  notifier          // nil if no more records will be appended
+	durable   func(uint64) bool // test whether record is durable
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// notifier tells tailing reader when new records may be visible.
+type notifier interface {
+	// changed returns a channel closed when new records may be
+	// visible, and a channel closed when no more records appended.
+	changed() (changed <-chan struct{}, done <-chan struct{})
+	// release is called when reader is closed.
+	release()
+	// failed returns the error stops appending records, if any.
+	failed() error
+	// truncated test whether the records read by r are discarded
+	// by truncation, it's called before returning records.
+	truncated(r *Reader) (bool, error)
+}
+
+// walNotifier notifies readers tailing wal in process.
+type walNotifier struct {
+	wal     *Wal
+	durable bool // notify when records are synced
+	tailer  *tailer
+}
+
+// tailer is the state of tailing reader shared with wal.
+type tailer struct {
+	// the least index discarded by truncation since last check,
+	// zero if none, atomic.
+	discarded uint64
+}
+
+// discard set the least index discarded to index if it's less.
+func (t *tailer) discard(index uint64) {
+	for {
+		old := atomic.LoadUint64(&t.discarded)
+		if (old != 0 && old <= index) ||
+			atomic.CompareAndSwapUint64(&t.discarded, old, index) {
+			return
+		}
+	}
+}
+
+func (n walNotifier) changed() (<-chan struct{}, <-chan struct{}) {
+	if n.durable {
+		return n.wal.durableChanged(), n.wal.done
+	}
+	return n.wal.changed(), n.wal.done
+}
+
+func (n walNotifier) release() {
+	atomic.AddInt32(&n.wal.tailers, -1)
+	n.wal.notifyMu.Lock()
+	delete(n.wal.tails, n.tailer)
+	n.wal.notifyMu.Unlock()
+}
+
+func (n walNotifier) failed() error {
+	return n.wal.Err()
+}
+
+func (n walNotifier) truncated(r *Reader) (bool, error) {
+	index := atomic.SwapUint64(&n.tailer.discarded, 0)
+	if index == 0 {
+		return false, nil
+	} else if r.readNext > index {
+		return true, nil
+	}
+	// the records read are kept, but the buffered ones may not.
+	return false, r.file.Reset()
+}
+
+// NewReader returns Reader starts at record with index from.
+func NewReader(dir string, from uint64) (*Reader, error) {
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		return nil, err
+	}
+	index, ok := searchIndex(names, from)
+	if !ok || !isValidSequences(names[index:]) {
+		return nil, ErrFileNotFound
+	}
+
+	r := &Reader{dir: dir, from: from, stop: make(chan struct{})}
+	r.assembler = record.NewAssembler(func(index uint64, data []byte) error {
+		if index >= r.from {
+			r.queue = append(r.queue, Entry{Index: index, Data: data})
+		}
+		return nil
+	})
+
+	seq, idx := mustParseWalName(names[index])
+	if err = r.open(seq, idx); err != nil {
+		return nil, err
+	}
+	if from > idx {
+		if err = r.file.SeekIndex(from); err != nil {
+			r.file.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Tail returns Reader starts at record with index from, which follows
+// the records appended to wal, Next blocks until the next record is
+// written, or wal or reader is closed. It never returns the records
+// discarded before it, but the ones discarded by TruncateAfter after
+// they are read can't be recalled, Next returns false and Err wraps
+// ErrTruncated then. If wal fails, Next returns false and Err wraps
+// ErrWalFailed.
+func (wal *Wal) Tail(from uint64) (*Reader, error) {
+	return wal.tail(from, false)
+}
+
+// TailDurable same as Tail, but the records are returned only
+// after they are synced, see LastSyncedIndex.
+func (wal *Wal) TailDurable(from uint64) (*Reader, error) {
+	return wal.tail(from, true)
+}
+
+func (wal *Wal) tail(from uint64, durable bool) (*Reader, error) {
+	// counted before flushing, so the records written after
+	// flushing are flushed and notified by service, and the
+	// truncation after flushing is told.
+	n := walNotifier{wal: wal, durable: durable, tailer: &tailer{}}
+	atomic.AddInt32(&wal.tailers, 1)
+	wal.notifyMu.Lock()
+	wal.tails[n.tailer] = struct{}{}
+	wal.notifyMu.Unlock()
+
+	// make the written records visible.
+	cmd, ch := genFlush()
+	wal.queue <- cmd
+	if err := <-ch; err != nil {
+		n.release()
+		return nil, err
+	}
+
+	r, err := NewReader(wal.walDir, from)
+	if err != nil {
+		n.release()
+		return nil, err
+	}
+	r.notifier = n
+	if durable {
+		r.durable = func(index uint64) bool {
+			return index < atomic.LoadUint64(&wal.syncedNext)
+		}
+	}
+	return r, nil
+}
+
+// Next move to the next record, it returns false if there no more
+// records or error occurs, see Err.
+func (r *Reader) Next() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for !r.ready() {
+		if r.err != nil {
+			return false
+		}
+
+		// the notification must be taken before reading, so
+		// it's never missed.
+		var changed, done <-chan struct{}
+		if r.notifier != nil {
+			changed, done = r.notifier.changed()
+			if r.err = r.notifier.failed(); r.err != nil {
+				return false
+			}
+		}
+		if len(r.queue) == 0 {
+			if r.read() {
+				continue
+			} else if r.err != nil {
+				return false
+			}
+		}
+		if changed == nil {
+			return false
+		}
+
+		finished := false
+		r.mu.Unlock()
+		select {
+		case <-changed:
+		case <-done:
+			finished = true
+		case <-r.stop:
+		}
+		r.mu.Lock()
+
+		if r.file == nil {
+			r.err = ErrReaderClosed
+		} else if finished {
+			// read the rest of records.
+			r.notifier.release()
+			r.notifier = nil
+		}
+	}
+
+	// the records read may be discarded by truncation in the middle.
+	if r.notifier != nil {
+		truncated, err := r.notifier.truncated(r)
+		if truncated {
+			err = r.truncatedError(record.ErrStale)
+		}
+		if err != nil {
+			r.err = err
+			return false
+		}
+	}
+
+	r.entry = r.queue[0]
+	r.queue = r.queue[1:]
+	return true
+}
+
+// ready test whether the next record could be returned.
+func (r *Reader) ready() bool {
+	return len(r.queue) > 0 && (r.durable == nil || r.durable(r.queue[0].Index))
+}
+
+// Index returns index of current record.
+func (r *Reader) Index() uint64 {
+	return r.entry.Index
+}
+
+// Data returns data of current record.
+func (r *Reader) Data() []byte {
+	return r.entry.Data
+}
+
+// Err returns the error stops Next.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+// Close release the file opened by reader, it could be called
+// by others to stop Next blocked by tailing.
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	if r.notifier != nil {
+		r.notifier.release()
+		r.notifier = nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// read read the next record of files, and returns false if
+// there no more records for now.
+func (r *Reader) read() bool {
+	err := r.readFile()
+	if err == nil {
+		return true
+	} else if err != io.EOF && !record.IsCorrupted(err) {
+		r.err = r.truncatedError(err)
+		return false
+	}
+
+	// the records after the ones read may be truncated, so
+	// nothing follows them.
+	if stale, err := r.file.Stale(); err != nil || stale {
+		if stale {
+			err = r.truncatedError(record.ErrStale)
+		}
+		r.err = err
+		return false
+	}
+
+	// move to the next file if exists, the torn or corrupted
+	// records at the end of the last file are being written.
+	next, ok, err := r.lookupNext()
+	if err != nil || !ok {
+		r.err = err
+		return false
+	}
+
+	// current file is synced before the next one is created,
+	// so read it again, the rest of records must be visible.
+	if err = r.readFile(); err != io.EOF {
+		r.err = r.truncatedError(err)
+		return err == nil
+	}
+	if err = r.open(r.seq+1, next); err != nil {
+		r.err = err
+		return false
+	}
+	return true
+}
+
+// truncatedError returns ErrTruncated if err means the records read
+// are stale, otherwise err.
+func (r *Reader) truncatedError(err error) error {
+	if errors.Is(err, record.ErrStale) {
+		return fmt.Errorf("%s: %w", walName(r.seq, r.file.Header().Index), ErrTruncated)
+	}
+	return err
+}
+
+func (r *Reader) readFile() error {
+	var rec record.Record
+	if err := r.file.Next(&rec); err != nil {
+		return err
+	}
+	r.readNext = rec.Index + 1
+	return r.assembler.Visit(&rec)
+}
+
+// lookupNext returns index of the file follows current file. If it's
+// released by writer before reading, ErrFileNotFound is returned. If
+// current file is removed by truncation, ErrTruncated is returned.
+func (r *Reader) lookupNext() (uint64, bool, error) {
+	names, err := readAllWalNames(r.dir, DefaultOptions().Logger)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, name := range names {
+		seq, idx := mustParseWalName(name)
+		if seq == r.seq+1 {
+			return idx, true, nil
+		} else if seq > r.seq+1 {
+			return 0, false, fmt.Errorf("wal file of sequence %d: %w", r.seq+1, ErrFileNotFound)
+		}
+	}
+	// the last file is never released.
+	if len(names) == 0 {
+		return 0, false, r.truncatedError(record.ErrStale)
+	}
+	if seq, _ := mustParseWalName(names[len(names)-1]); seq < r.seq {
+		return 0, false, r.truncatedError(record.ErrStale)
+	}
+	return 0, false, nil
+}
+
+func (r *Reader) open(seq, idx uint64) error {
+	file, err := record.OpenReader(filepath.Join(r.dir, walName(seq, idx)))
+	if err != nil {
+		return err
+	}
+	header := file.Header()
+	if header.Sequence != seq || header.Index != idx {
+		file.Close()
+		return fmt.Errorf("%s: %w", walName(seq, idx), ErrHeaderMismatch)
+	}
+
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file, r.seq = file, seq
+	return nil
+}
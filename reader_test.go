@@ -0,0 +1,120 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readAll(t *testing.T, dir string, from uint64) []uint64 {
+	reader, err := NewReader(dir, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	indexes := make([]uint64, 0)
+	for reader.Next() {
+		if reader.Data()[0] != byte(reader.Index()) {
+			t.Fatalf("data of %d = %d", reader.Index(), reader.Data()[0])
+		}
+		indexes = append(indexes, reader.Index())
+	}
+	if err = reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return indexes
+}
+
+func TestReader(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	for i := 0; i < 20; i++ {
+		data := make([]byte, 300)
+		data[0] = byte(i)
+		if err = <-wal.Write(uint64(i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	batch := NewBatch()
+	for i := 20; i < 30; i++ {
+		data := make([]byte, 300)
+		data[0] = byte(i)
+		batch.Add(uint64(i), data)
+	}
+	if err = <-wal.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	// reader works while wal is locked by writer, but only
+	// the synced records are visible.
+	if err = <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, from := range []uint64{0, 5, 13, 22, 29} {
+		indexes := readAll(t, dir, from)
+		if len(indexes) != int(30-from) {
+			t.Fatalf("from %d: read %d records, want %d", from, len(indexes), 30-from)
+		}
+		for i, index := range indexes {
+			if index != from+uint64(i) {
+				t.Fatalf("from %d: index = %d, want %d", from, index, from+uint64(i))
+			}
+		}
+	}
+
+	if _, err = NewReader(filepath.Join(dir, "none"), 0); err == nil {
+		t.Errorf("want error for missing dir")
+	}
+}
+
+func TestReader_Continue(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	reader, err := NewReader(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var next uint64
+	for round := 0; round < 3; round++ {
+		// records cross files in each round.
+		for i := 0; i < 20; i++ {
+			if err = <-wal.Write(next+uint64(i), make([]byte, 300)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = <-wal.Sync(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 20; i++ {
+			if !reader.Next() {
+				t.Fatalf("round %d: stop at %d: %v", round, next, reader.Err())
+			}
+			if reader.Index() != next {
+				t.Fatalf("index = %d, want %d", reader.Index(), next)
+			}
+			next++
+		}
+		if reader.Next() {
+			t.Fatalf("round %d: read %d, want no more records", round, reader.Index())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package record
+
+import "encoding/binary"
+
+// Assembler assembles the records passed by Replay, and pushes
+// user records to consumer. Records of batch are held until the
+// commit of batch is visited, records of batch without commit
+// are discarded.
+type Assembler struct {
+	consumer Consumer
+	pending  []Record
+	visited  bool // any records visited
+	partial  bool // pending starts at the first visited record
+}
+
+// NewAssembler returns Assembler push records to consumer.
+func NewAssembler(consumer Consumer) *Assembler {
+	return &Assembler{consumer: consumer}
+}
+
+// Visit is a Visitor, see Replay.
+func (a *Assembler) Visit(record *Record) error {
+	first := !a.visited
+	a.visited = true
+
+	switch record.Type {
+	case RecordBatch:
+		if len(a.pending) == 0 {
+			// replay may start at the middle of batch, whose
+			// head is in the previous file.
+			a.partial = first
+		}
+		a.pending = append(a.pending, *record)
+		return nil
+
+	case RecordCommit:
+		count := len(a.pending)
+		if len(record.Data) >= 4 {
+			count = int(binary.LittleEndian.Uint32(record.Data))
+		}
+		records := a.pending
+		if count <= len(records) {
+			records = records[len(records)-count:]
+		} else if !a.partial {
+			// the head of batch is lost.
+			records = nil
+		}
+		a.pending = a.pending[:0]
+		for i := range records {
+			if err := a.consumer(records[i].Index, records[i].Data); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		// records of batch before a full record never be committed.
+		a.pending = a.pending[:0]
+		return a.consumer(record.Index, record.Data)
+	}
+}
+
+// Pending returns records of batch which wait for commit.
+func (a *Assembler) Pending() []Record {
+	return a.pending
+}
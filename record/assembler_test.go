@@ -0,0 +1,91 @@
+package record
+
+import (
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func commitRecord(index uint64, count int) Record {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, uint32(count))
+	return Record{Type: RecordCommit, Index: index, Data: data}
+}
+
+func TestAssembler(t *testing.T) {
+	full := func(index uint64) Record {
+		return Record{Type: RecordFull, Index: index, Data: []byte{1}}
+	}
+	batch := func(index uint64) Record {
+		return Record{Type: RecordBatch, Index: index, Data: []byte{1}}
+	}
+
+	tests := []struct {
+		records []Record
+		want    []uint64
+		pending int
+	}{
+		{[]Record{full(1), batch(2), batch(3), commitRecord(3, 2), full(4)}, []uint64{1, 2, 3, 4}, 0},
+		// batch without commit.
+		{[]Record{full(1), batch(2), batch(3)}, []uint64{1}, 2},
+		// orphan records followed by full record.
+		{[]Record{batch(1), batch(2), full(1)}, []uint64{1}, 0},
+		// orphan records followed by another batch.
+		{[]Record{batch(1), batch(2), batch(1), commitRecord(1, 1)}, []uint64{1}, 0},
+		// replay starts at the middle of batch.
+		{[]Record{batch(2), batch(3), commitRecord(3, 3), full(4)}, []uint64{2, 3, 4}, 0},
+		// the head of batch is lost.
+		{[]Record{full(1), batch(3), commitRecord(3, 2)}, []uint64{1}, 0},
+	}
+	for i, test := range tests {
+		get := make([]uint64, 0)
+		assembler := NewAssembler(func(index uint64, data []byte) error {
+			get = append(get, index)
+			return nil
+		})
+		for j := range test.records {
+			if err := assembler.Visit(&test.records[j]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(get, test.want) {
+			t.Errorf("#%d: get %v, want %v", i, get, test.want)
+		}
+		if len(assembler.Pending()) != test.pending {
+			t.Errorf("#%d: pending = %d, want %d", i, len(assembler.Pending()), test.pending)
+		}
+	}
+}
+
+func TestFile_RestoreBatch(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+
+	file.Write(1, []byte{1})
+	file.WriteBatch(2, []byte{2})
+	file.WriteBatch(3, []byte{3})
+	file.WriteCommit(3, 2)
+	file.WriteBatch(4, []byte{4})
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := make([]uint64, 0)
+	file, err = RestoreFile(filename, 0, func(index uint64, data []byte) error {
+		get = append(get, index)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if !reflect.DeepEqual(get, []uint64{1, 2, 3}) {
+		t.Errorf("get %v, want [1 2 3]", get)
+	}
+}
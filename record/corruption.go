@@ -0,0 +1,39 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CorruptionError describes a torn or corrupted record found when
+// reading file, Err is one of ErrUnexpectedEOF, ErrBadChecksum and
+// ErrBadRecordType, so it could be tested by errors.Is.
+type CorruptionError struct {
+	// File is the path of record file.
+	File string
+	// Offset is the offset of corrupted record in file.
+	Offset int64
+	// Index is the index expected at offset, that is the index of
+	// last valid record plus one, zero if it's unknown.
+	Index uint64
+	// Err is the cause.
+	Err error
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("corrupted record of %s at offset %d, index %d: %v",
+		e.File, e.Offset, e.Index, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *CorruptionError) Unwrap() error {
+	return e.Err
+}
+
+// IsCorrupted reports whether err means that the records of
+// file is torn or corrupted.
+func IsCorrupted(err error) bool {
+	return errors.Is(err, ErrUnexpectedEOF) ||
+		errors.Is(err, ErrBadChecksum) ||
+		errors.Is(err, ErrBadRecordType)
+}
@@ -0,0 +1,115 @@
+package record
+
+import (
+	"io"
+	"os"
+
+	"github.com/thinkermao/wal-go/file"
+)
+
+// writer buffers records written to file.
+type writer interface {
+	Write(bytes []byte) error
+	Flush() error
+}
+
+// directWriter writes records to file opened with O_DIRECT, by a
+// buffer aligned to file.DirectAlignment. Every flush is padded with
+// zeros to the alignment, and the last partial block is kept in buffer
+// and written again by next flush, so the padding is overwritten by
+// the following records. The padding reads as the end of records.
+type directWriter struct {
+	fd      *os.File
+	buf     []byte // aligned, len(buf) is capacity
+	base    int64  // offset of buf[0] in file, aligned
+	n       int    // bytes in buf
+	flushed int    // bytes of buf written to file
+}
+
+func newDirectWriter(filename string, size int, perm os.FileMode) (*directWriter, error) {
+	fd, err := file.OpenDirect(filename, os.O_RDWR, perm)
+	if err != nil {
+		return nil, err
+	}
+	if size < file.DirectAlignment {
+		size = file.DirectAlignment
+	}
+	return &directWriter{
+		fd:  fd,
+		buf: file.AlignedBlock(alignUp(int64(size))),
+	}, nil
+}
+
+func alignUp(n int64) int {
+	return int((n + file.DirectAlignment - 1) &^ (file.DirectAlignment - 1))
+}
+
+func alignDown(n int64) int64 {
+	return n &^ (file.DirectAlignment - 1)
+}
+
+// Write append bytes to buffer, the buffer is flushed if it's full.
+func (w *directWriter) Write(bytes []byte) error {
+	for len(bytes) > 0 {
+		if w.n == len(w.buf) {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		n := copy(w.buf[w.n:], bytes)
+		w.n += n
+		bytes = bytes[n:]
+	}
+	return nil
+}
+
+// Flush write the buffered bytes padded with zeros to file.
+func (w *directWriter) Flush() error {
+	if w.n == w.flushed {
+		return nil
+	}
+
+	end := alignUp(int64(w.n))
+	for i := w.n; i < end; i++ {
+		w.buf[i] = 0
+	}
+	if _, err := w.fd.WriteAt(w.buf[:end], w.base); err != nil {
+		return err
+	}
+
+	// keep the last partial block.
+	full := int(alignDown(int64(w.n)))
+	copy(w.buf, w.buf[full:w.n])
+	w.base += int64(full)
+	w.n -= full
+	w.flushed = w.n
+	return nil
+}
+
+// reset drop buffered bytes and move to offset, the bytes of the block
+// contains offset are read back, so they are written again with the
+// following records. The bytes after offset are discarded.
+func (w *directWriter) reset(offset int64) error {
+	w.base = alignDown(offset)
+	w.n = int(offset - w.base)
+	w.flushed = w.n
+	if w.n == 0 {
+		return nil
+	}
+
+	n, err := w.fd.ReadAt(w.buf[:file.DirectAlignment], w.base)
+	if err == io.EOF && n >= w.n {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+	// the bytes after offset are rewritten by next flush.
+	w.flushed = 0
+	return nil
+}
+
+// Close close file.
+func (w *directWriter) Close() error {
+	return w.fd.Close()
+}
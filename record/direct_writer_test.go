@@ -0,0 +1,154 @@
+package record
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func createDirectFile(t *testing.T, filename string) *File {
+	opts := DefaultOptions()
+	opts.Size = 64 * 1024
+	opts.Direct = true
+	file, err := CreateFileWithOptions(filename, 0, 1, opts)
+	if err != nil {
+		t.Skipf("direct io isn't supported: %v", err)
+	}
+	return file
+}
+
+func directRecord(idx uint64) []byte {
+	return bytes.Repeat([]byte{byte(idx)}, int(idx*37%500)+1)
+}
+
+func restoreDirect(t *testing.T, filename string, opts *Options) (*File, uint64, error) {
+	file, err := OpenFileWithOptions(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := uint64(1)
+	err = file.Restore(0, func(index uint64, data []byte) error {
+		if index != next || !bytes.Equal(data, directRecord(index)) {
+			t.Fatalf("record %d = %d bytes, want record %d", index, len(data), next)
+		}
+		next++
+		return nil
+	})
+	return file, next - 1, err
+}
+
+func TestFile_Direct(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file := createDirectFile(t, filename)
+	defer os.Remove(filename)
+
+	for idx := uint64(1); idx <= 100; idx++ {
+		if err := file.Write(idx, directRecord(idx)); err != nil {
+			t.Fatal(err)
+		}
+		switch idx % 7 {
+		case 0:
+			if err := file.Sync(); err != nil {
+				t.Fatal(err)
+			}
+		case 3:
+			if err := file.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	offset := file.Offset()
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// appends to the reopened file.
+	opts := DefaultOptions()
+	opts.Size = 64 * 1024
+	opts.Direct = true
+	file, count, err := restoreDirect(t, filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 100 || file.Offset() != offset {
+		t.Fatalf("count = %d, offset = %d, want 100, %d", count, file.Offset(), offset)
+	}
+	for idx := uint64(101); idx <= 120; idx++ {
+		if err = file.Write(idx, directRecord(idx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, count, err = restoreDirect(t, filename, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if count != 120 {
+		t.Errorf("count = %d, want 120", count)
+	}
+}
+
+func TestFile_DirectTornPadding(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file := createDirectFile(t, filename)
+	defer os.Remove(filename)
+
+	for idx := uint64(1); idx <= 10; idx++ {
+		if err := file.Write(idx, directRecord(idx)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	offset := file.Offset()
+	file.Close()
+
+	// the next flush is torn, only a part of record 11 is
+	// written into the padding.
+	data := directRecord(11)
+	frame := make([]byte, frameHeaderSize+len(data))
+	encodeFrameHeader(frame, RecordFull, 11, data)
+	copy(frame[frameHeaderSize:], data)
+	fd, err := os.OpenFile(filename, os.O_RDWR, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fd.WriteAt(frame[:len(frame)/2], offset); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	opts := DefaultOptions()
+	opts.Size = 64 * 1024
+	opts.Direct = true
+	file, count, err := restoreDirect(t, filename, opts)
+	if !IsCorrupted(err) {
+		t.Fatalf("want corrupted, get: %v", err)
+	}
+	if count != 10 || file.Offset() != offset {
+		t.Errorf("count = %d, offset = %d, want 10, %d", count, file.Offset(), offset)
+	}
+	if err = file.Truncate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Write(11, data); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, count, err = restoreDirect(t, filename, DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if count != 11 {
+		t.Errorf("count = %d, want 11", count)
+	}
+}
@@ -0,0 +1,85 @@
+package record
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Every record is stored as a frame, all integers are little endian:
+//
+//	+----------+----------+----------+--------+-----------------+
+//	|  length  |  crc32c  |  index   |  type  |     payload     |
+//	| 4 bytes  | 4 bytes  | 8 bytes  | 1 byte |  length bytes   |
+//	+----------+----------+----------+--------+-----------------+
+//
+// crc32c is the CRC-32 (Castagnoli) of every byte of the frame except
+// itself, that is length, index, type and payload in this order.
+// See FORMAT.md for the full specification.
+const (
+	frameHeaderSize = 17
+
+	frameLengthOffset = 0
+	frameCrcOffset    = 4
+	frameIndexOffset  = 8
+	frameTypeOffset   = 16
+)
+
+// RecordType tells what the payload of a frame is.
+type RecordType uint8
+
+const (
+	// recordZero never be written, a frame header filled with zero
+	// means there no more records, the rest of file is preallocated.
+	recordZero RecordType = iota
+	// RecordFull is a complete user record.
+	RecordFull
+	// RecordBatch is a user record belongs to a batch, it's
+	// valid only if the commit of batch is found.
+	RecordBatch
+	// RecordCommit marks the end of a batch, index is the index of
+	// last record in batch, payload is the number of records of
+	// batch in uint32.
+	RecordCommit
+)
+
+func (typ RecordType) isValid() bool {
+	return typ >= RecordFull && typ <= RecordCommit
+}
+
+var crc32Table = crc32.MakeTable(crc32.Castagnoli)
+
+// encodeFrameHeader fill dst with frame header of record, dst must
+// have at least frameHeaderSize bytes. It does no allocation.
+func encodeFrameHeader(dst []byte, typ RecordType, index uint64, data []byte) {
+	binary.LittleEndian.PutUint32(dst[frameLengthOffset:], uint32(len(data)))
+	binary.LittleEndian.PutUint64(dst[frameIndexOffset:], index)
+	dst[frameTypeOffset] = byte(typ)
+	binary.LittleEndian.PutUint32(dst[frameCrcOffset:], frameChecksum(dst, data))
+}
+
+// decodeFrameHeader parse frame header from src, src must have at
+// least frameHeaderSize bytes. It does no allocation.
+func decodeFrameHeader(src []byte) (length uint32, crc uint32, index uint64, typ RecordType) {
+	length = binary.LittleEndian.Uint32(src[frameLengthOffset:])
+	crc = binary.LittleEndian.Uint32(src[frameCrcOffset:])
+	index = binary.LittleEndian.Uint64(src[frameIndexOffset:])
+	typ = RecordType(src[frameTypeOffset])
+	return
+}
+
+// isZeroFrameHeader test whether header is never written.
+func isZeroFrameHeader(header []byte) bool {
+	for _, b := range header[:frameHeaderSize] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// frameChecksum computes checksum of frame with header and payload.
+func frameChecksum(header []byte, data []byte) uint32 {
+	crc := crc32.Checksum(header[frameLengthOffset:frameCrcOffset], crc32Table)
+	crc = crc32.Update(crc, crc32Table, header[frameIndexOffset:frameHeaderSize])
+	return crc32.Update(crc, crc32Table, data)
+}
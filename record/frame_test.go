@@ -0,0 +1,116 @@
+package record
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestFrameHeader_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		typ   RecordType
+		index uint64
+		data  []byte
+	}{
+		{RecordFull, 0, []byte{0x1}},
+		{RecordFull, 1, []byte{0x1, 0x2, 0x3}},
+		{RecordFull, 1<<64 - 1, make([]byte, 1000)},
+	}
+
+	for i, test := range tests {
+		header := [frameHeaderSize]byte{}
+		encodeFrameHeader(header[:], test.typ, test.index, test.data)
+
+		length, crc, index, typ := decodeFrameHeader(header[:])
+		if length != uint32(len(test.data)) {
+			t.Errorf("#%d: length = %d, want %d", i, length, len(test.data))
+		}
+		if index != test.index {
+			t.Errorf("#%d: index = %d, want %d", i, index, test.index)
+		}
+		if typ != test.typ {
+			t.Errorf("#%d: type = %d, want %d", i, typ, test.typ)
+		}
+		if crc != frameChecksum(header[:], test.data) {
+			t.Errorf("#%d: bad checksum", i)
+		}
+		if isZeroFrameHeader(header[:]) {
+			t.Errorf("#%d: want not zero header", i)
+		}
+	}
+}
+
+func TestFrameHeader_Checksum(t *testing.T) {
+	data := []byte{0x1, 0x2, 0x3}
+	header := [frameHeaderSize]byte{}
+	encodeFrameHeader(header[:], RecordFull, 10, data)
+	_, crc, _, _ := decodeFrameHeader(header[:])
+
+	for i := 0; i < frameHeaderSize; i++ {
+		if i >= frameCrcOffset && i < frameIndexOffset {
+			continue
+		}
+		corrupted := header
+		corrupted[i] ^= 0x1
+		if frameChecksum(corrupted[:], data) == crc {
+			t.Errorf("flip header byte %d, checksum not changed", i)
+		}
+	}
+
+	corrupted := []byte{0x1, 0x2, 0x4}
+	if frameChecksum(header[:], corrupted) == crc {
+		t.Errorf("flip payload, checksum not changed")
+	}
+}
+
+func TestFrameHeader_NoAllocation(t *testing.T) {
+	data := make([]byte, 100)
+	header := [frameHeaderSize]byte{}
+	allocs := testing.AllocsPerRun(100, func() {
+		encodeFrameHeader(header[:], RecordFull, 1, data)
+		decodeFrameHeader(header[:])
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func TestFile_WriteNoAllocation(t *testing.T) {
+	filename := "/tmp/xxxxxx"
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	defer file.Close()
+
+	data := bytes.Repeat([]byte{0x1}, 100)
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := file.Write(1, data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("allocs = %v, want 0", allocs)
+	}
+}
+
+func BenchmarkFile_Write(b *testing.B) {
+	filename := "/tmp/xxxxxx"
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.Remove(filename)
+	defer file.Close()
+
+	data := bytes.Repeat([]byte{0x1}, 100)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := file.Write(uint64(i), data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"time"
+)
+
+// Every record file starts with a header, all integers are little endian:
+//
+//	+--------+---------+----------+----------+-------+---------+----------+--------+
+//	| magic  | version | reserved | sequence | index | created | reserved | crc32c |
+//	|   4    |    2    |    2     |    8     |   8   |    8    |    28    |   4    |
+//	+--------+---------+----------+----------+-------+---------+----------+--------+
+//
+// crc32c is the CRC-32 (Castagnoli) of the first 60 bytes.
+// See FORMAT.md for the full specification.
+const (
+	headerSize = 64
+
+	headerMagicOffset    = 0
+	headerVersionOffset  = 4
+	headerSequenceOffset = 8
+	headerIndexOffset    = 16
+	headerCreatedOffset  = 24
+	headerCrcOffset      = 60
+
+	headerMagic   = 0x474c4157 // "WALG"
+	formatVersion = 1          // current version of file format
+)
+
+var (
+	// ErrBadHeader means file isn't a record file, or its header
+	// is corrupted.
+	ErrBadHeader = errors.New("bad record file header")
+	// ErrUnsupportedVersion means file is written by newer version.
+	ErrUnsupportedVersion = errors.New("unsupported record file version")
+)
+
+// Header describes a record file.
+type Header struct {
+	// Version of file format.
+	Version uint16
+	// Sequence of file in wal.
+	Sequence uint64
+	// Index of the first record in file.
+	Index uint64
+	// Created is the time file created.
+	Created time.Time
+}
+
+func encodeHeader(dst []byte, header *Header) {
+	for i := range dst[:headerSize] {
+		dst[i] = 0
+	}
+	binary.LittleEndian.PutUint32(dst[headerMagicOffset:], headerMagic)
+	binary.LittleEndian.PutUint16(dst[headerVersionOffset:], header.Version)
+	binary.LittleEndian.PutUint64(dst[headerSequenceOffset:], header.Sequence)
+	binary.LittleEndian.PutUint64(dst[headerIndexOffset:], header.Index)
+	binary.LittleEndian.PutUint64(dst[headerCreatedOffset:], uint64(header.Created.UnixNano()))
+	crc := crc32.Checksum(dst[:headerCrcOffset], crc32Table)
+	binary.LittleEndian.PutUint32(dst[headerCrcOffset:], crc)
+}
+
+func decodeHeader(src []byte, header *Header) error {
+	if binary.LittleEndian.Uint32(src[headerMagicOffset:]) != headerMagic {
+		return ErrBadHeader
+	}
+	crc := crc32.Checksum(src[:headerCrcOffset], crc32Table)
+	if binary.LittleEndian.Uint32(src[headerCrcOffset:]) != crc {
+		return ErrBadHeader
+	}
+
+	header.Version = binary.LittleEndian.Uint16(src[headerVersionOffset:])
+	if header.Version != formatVersion {
+		return ErrUnsupportedVersion
+	}
+	header.Sequence = binary.LittleEndian.Uint64(src[headerSequenceOffset:])
+	header.Index = binary.LittleEndian.Uint64(src[headerIndexOffset:])
+	created := int64(binary.LittleEndian.Uint64(src[headerCreatedOffset:]))
+	header.Created = time.Unix(0, created)
+	return nil
+}
@@ -0,0 +1,104 @@
+package record
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHeader_EncodeDecode(t *testing.T) {
+	want := Header{
+		Version:  formatVersion,
+		Sequence: 10,
+		Index:    1000,
+		Created:  time.Unix(0, 1234567890),
+	}
+
+	buf := [headerSize]byte{}
+	encodeHeader(buf[:], &want)
+
+	var get Header
+	if err := decodeHeader(buf[:], &get); err != nil {
+		t.Fatal(err)
+	}
+	if get != want {
+		t.Errorf("header = %+v, want %+v", get, want)
+	}
+}
+
+func TestHeader_Decode(t *testing.T) {
+	header := Header{Version: formatVersion, Sequence: 1, Index: 2}
+	valid := [headerSize]byte{}
+	encodeHeader(valid[:], &header)
+
+	newVersion := header
+	newVersion.Version = formatVersion + 1
+	unsupported := [headerSize]byte{}
+	encodeHeader(unsupported[:], &newVersion)
+
+	corrupted := valid
+	corrupted[headerIndexOffset] ^= 0x1
+
+	badMagic := valid
+	badMagic[headerMagicOffset] = 'X'
+
+	tests := []struct {
+		buf  [headerSize]byte
+		werr error
+	}{
+		{valid, nil},
+		{unsupported, ErrUnsupportedVersion},
+		{corrupted, ErrBadHeader},
+		{badMagic, ErrBadHeader},
+		{[headerSize]byte{}, ErrBadHeader},
+	}
+	for i, test := range tests {
+		var get Header
+		if err := decodeHeader(test.buf[:], &get); !errors.Is(err, test.werr) {
+			t.Errorf("#%d: err = %v, want %v", i, err, test.werr)
+		}
+	}
+}
+
+func TestFile_Header(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file, err := CreateFile(filename, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	header := file.Header()
+	if header.Version != formatVersion || header.Sequence != 3 || header.Index != 100 {
+		t.Errorf("header = %+v, want version %d, sequence 3, index 100",
+			header, formatVersion)
+	}
+	if file.LastIndex() != 99 {
+		t.Errorf("last index = %d, want 99", file.LastIndex())
+	}
+}
+
+func TestFile_OpenForeign(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	fd, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	fd.Write([]byte("this is not a record file"))
+	fd.Close()
+
+	if _, err = OpenFile(filename); !errors.Is(err, ErrBadHeader) {
+		t.Errorf("err = %v, want %v", err, ErrBadHeader)
+	}
+}
@@ -0,0 +1,217 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thinkermao/wal-go/file"
+)
+
+// ErrStale means the records read by Reader are overwritten or the
+// file is replaced, such as the records are truncated by writer.
+var ErrStale = errors.New("records read are stale")
+
+// Reader reads records of file one by one. It opens file read-only
+// and takes no lock, so file could be written by others at the same
+// time, only the records flushed by writer are visible.
+type Reader struct {
+	filename string
+	fd       *os.File
+	buffer   *file.Buffer
+	meta     Header
+	offset   int64  // offset of next record
+	next     uint64 // index of next record, zero if unknown
+	size     int64  // size of file when last stat
+	header   [frameHeaderSize]byte
+	last     [frameHeaderSize]byte // header of the last record read
+	lastAt   int64                 // offset of the last record read, zero if none
+}
+
+// OpenReader open record file for reading, and verify its header.
+func OpenReader(filename string) (*Reader, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{
+		filename: filename,
+		fd:       fd,
+		buffer:   file.BufferCreate(fd),
+		offset:   headerSize,
+	}
+
+	buf := [headerSize]byte{}
+	if err = reader.buffer.Read(buf[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrBadHeader
+		}
+	} else if err = decodeHeader(buf[:], &reader.meta); err == nil {
+		reader.next = reader.meta.Index
+		err = reader.reload(headerSize)
+	}
+	if err != nil {
+		fd.Close()
+		return nil, fmt.Errorf("open %s: %w", filename, err)
+	}
+	return reader, nil
+}
+
+// Header returns header of record file.
+func (r *Reader) Header() Header {
+	return r.meta
+}
+
+// Offset returns the offset of next record.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// SeekTo move to the record at offset, offset must be the
+// start of a record, or the end of records.
+func (r *Reader) SeekTo(offset int64) error {
+	r.next = 0
+	if offset == headerSize {
+		r.next = r.meta.Index
+	}
+	return r.seek(offset)
+}
+
+func (r *Reader) seek(offset int64) error {
+	r.buffer.Reset()
+	if _, err := r.fd.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.offset = offset
+	return nil
+}
+
+// SeekIndex move to the record near before the one at index by the
+// sidecar index file, it does nothing if there no sidecar.
+func (r *Reader) SeekIndex(index uint64) error {
+	entries, err := readIndexFile(r.filename)
+	if err != nil {
+		return nil
+	}
+	si := sparseIndex{entries: entries}
+	if entry, ok := si.lookup(index); ok {
+		if err = r.seek(entry.offset); err != nil {
+			return err
+		}
+		r.next = entry.index
+	}
+	return nil
+}
+
+// Next returns the next record, io.EOF means there no more records
+// for now, Next could be called again after others append records.
+// The torn or corrupted records, and the end of records are read
+// again before returning, because the buffered bytes may be read
+// before they are written.
+func (r *Reader) Next(record *Record) error {
+	offset := r.offset
+	length, rec, err := readRecord(r.buffer, r.header[:], r.size-offset)
+	if IsCorrupted(err) || (err == nil && length == 0) {
+		if err = r.reload(offset); err == nil {
+			length, rec, err = readRecord(r.buffer, r.header[:], r.size-offset)
+		}
+	}
+	if err == nil && length == 0 {
+		err = io.EOF
+	}
+	if err != nil {
+		// read the same position next time.
+		if serr := r.seek(offset); serr != nil {
+			return serr
+		}
+		if IsCorrupted(err) {
+			err = &CorruptionError{File: r.filename, Offset: offset, Index: r.next, Err: err}
+		}
+		return err
+	}
+
+	if r.next != 0 && !continuous(&rec, r.next) {
+		// the records after the ones read are rewritten.
+		if err = r.seek(offset); err != nil {
+			return err
+		}
+		return ErrStale
+	}
+
+	*record = rec
+	record.Offset = offset
+	r.offset = offset + length
+	r.next = rec.Index + 1
+	r.last, r.lastAt = r.header, offset
+	return nil
+}
+
+// continuous test whether rec follows the records before it, the
+// commit of batch has the index of the last record of batch.
+func continuous(rec *Record, next uint64) bool {
+	if rec.Type == RecordCommit {
+		return rec.Index+1 == next
+	}
+	return rec.Index == next
+}
+
+// Reset drop the buffered bytes, the following records are read
+// from file again.
+func (r *Reader) Reset() error {
+	return r.seek(r.offset)
+}
+
+// Stale test whether the last record read is overwritten, or the file
+// at filename is replaced by another one. A removed file isn't stale,
+// it may be released after reading.
+func (r *Reader) Stale() (bool, error) {
+	stat, err := os.Stat(r.filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	opened, err := r.fd.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(stat, opened) {
+		return true, nil
+	}
+	if r.lastAt == 0 {
+		return false, nil
+	}
+
+	header := [frameHeaderSize]byte{}
+	if _, err = r.fd.ReadAt(header[:], r.lastAt); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return !bytes.Equal(header[:], r.last[:]), nil
+}
+
+// reload drop buffered bytes and stat file again.
+func (r *Reader) reload(offset int64) error {
+	if err := r.stat(); err != nil {
+		return err
+	}
+	return r.seek(offset)
+}
+
+func (r *Reader) stat() error {
+	stat, err := r.fd.Stat()
+	if err != nil {
+		return err
+	}
+	r.size = stat.Size()
+	return nil
+}
+
+// Close close file.
+func (r *Reader) Close() error {
+	return r.fd.Close()
+}
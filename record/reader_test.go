@@ -0,0 +1,141 @@
+package record
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestReader_Next(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	defer file.Close()
+
+	reader, err := OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var record Record
+	if err = reader.Next(&record); err != io.EOF {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+
+	// reader works while file is locked by writer.
+	for i := 1; i <= 10; i++ {
+		if err = file.Write(uint64(i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = file.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	offset := reader.Offset()
+	for i := 1; i <= 10; i++ {
+		if err = reader.Next(&record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Index != uint64(i) || record.Data[0] != byte(i) {
+			t.Fatalf("record = %+v, want index %d", record, i)
+		}
+		if record.Offset != offset {
+			t.Fatalf("offset = %d, want %d", record.Offset, offset)
+		}
+		offset = reader.Offset()
+	}
+	if err = reader.Next(&record); err != io.EOF {
+		t.Fatalf("err = %v, want EOF", err)
+	}
+	if offset != file.Offset() {
+		t.Errorf("offset = %d, want %d", offset, file.Offset())
+	}
+}
+
+func TestReader_Grow(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	opts := DefaultOptions()
+	opts.Size = 128
+	file, err := CreateFileWithOptions(filename, 0, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	defer file.Close()
+
+	reader, err := OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	// the frame overflows the preallocated size.
+	if err = file.Write(1, make([]byte, 200)); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var record Record
+	if err = reader.Next(&record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Index != 1 || len(record.Data) != 200 {
+		t.Errorf("record = {%d, %d bytes}, want {1, 200 bytes}", record.Index, len(record.Data))
+	}
+}
+
+func TestReader_Corrupted(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	var offset int64
+	for idx := uint64(1); idx <= 3; idx++ {
+		offset = file.Offset()
+		if err = file.Write(idx, []byte{byte(idx)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	fd, err := os.OpenFile(filename, os.O_RDWR, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.WriteAt([]byte{0xff}, offset+frameHeaderSize)
+	fd.Close()
+
+	reader, err := OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var record Record
+	for i := 0; i < 2; i++ {
+		if err = reader.Next(&record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the corrupted record is read again next time.
+	for i := 0; i < 2; i++ {
+		err = reader.Next(&record)
+		var cerr *CorruptionError
+		if !errors.As(err, &cerr) || !errors.Is(err, ErrBadChecksum) {
+			t.Fatalf("err = %v, want CorruptionError of %v", err, ErrBadChecksum)
+		}
+		if cerr.File != filename || cerr.Offset != offset || cerr.Index != 3 {
+			t.Errorf("err = %+v, want index 3 at offset %d", cerr, offset)
+		}
+	}
+}
@@ -1,12 +1,14 @@
 package record
 
-type record struct {
-	Crc32 uint32
+// Record is a frame read from record file.
+type Record struct {
+	Type  RecordType
 	Index uint64
 	Data  []byte
+	// Offset of frame in file.
+	Offset int64
 }
 
-// Reset implements Messager interface.
-func (r *record) Reset() {
-	*r = record{}
-}
+// Visitor used by Replay, to visit all records of file,
+// includes records of batch and commit of batch.
+type Visitor func(record *Record) error
@@ -3,182 +3,598 @@ package record
 import (
 	"encoding/binary"
 	"errors"
-	"hash/crc32"
+	"fmt"
 	"io"
 	"os"
 	"sync/atomic"
+	"time"
 
 	"github.com/thinkermao/wal-go/file"
-	"github.com/thinkermao/wal-go/utils/pd"
 )
 
 const (
 	recordFileSize = 1024 * 1024 * 64 // Record file default size.
+	filePerm       = 0777             // Record file default permission.
+	tmpFileSuffix  = ".tmp"           // Suffix of record file being created.
 )
 
 var (
-	crc32Table = crc32.MakeTable(crc32.Koopman) // checksum use crc32
-
-	// errors returns when something bad.
-	errEmptyRecord   = errors.New("write empty record")
-	errUnexpectedEOF = errors.New("unexpected end of file")
-	errBadChecksum   = errors.New("bad checksum")
+	// ErrEmptyRecord is returned if write record without data.
+	ErrEmptyRecord = errors.New("write empty record")
+	// ErrUnexpectedEOF means the record is torn at end of file.
+	ErrUnexpectedEOF = errors.New("unexpected end of file")
+	// ErrBadChecksum means the record is corrupted.
+	ErrBadChecksum = errors.New("bad checksum")
+	// ErrBadRecordType means the type of record is unknown.
+	ErrBadRecordType = errors.New("bad record type")
 )
 
 // Consumer used by RestoreFile, to consume restored records.
 type Consumer func(index uint64, data []byte) error
 
+// Options used to create or open record file.
+type Options struct {
+	// Size is the size preallocated for file, and file is
+	// full when size of records great than it.
+	Size int64
+	// BufferSize is the size of write buffer.
+	BufferSize int
+	// Perm is the permission of created file.
+	Perm os.FileMode
+	// IndexInterval is the bytes of records between entries
+	// of sparse index, zero uses the default 64KB.
+	IndexInterval int64
+	// WritebackBytes is the bytes of records written between
+	// starting writeback in background, zero disables it.
+	WritebackBytes int64
+	// Direct opens file with O_DIRECT for writing records, so they
+	// bypass page cache, it's supported on linux only.
+	Direct bool
+}
+
+// DefaultOptions returns the options used by CreateFile and OpenFile.
+func DefaultOptions() *Options {
+	return &Options{
+		Size:          recordFileSize,
+		BufferSize:    file.DefaultBufferSize,
+		Perm:          filePerm,
+		IndexInterval: indexInterval,
+	}
+}
+
 // File is record file, it has buffer, and preallocated
 // recordFileSize whitespace when first create it.
 type File struct {
-	filename string
-	file     *file.LockFile
-	buffer   *file.Buffer
-	size     uint32
-	offset   uint32
+	offset    int64 // keep 64-bit aligned for atomic operations
+	size      int64
+	filename  string
+	file      *file.LockFile
+	buffer    *file.Buffer
+	perm      os.FileMode
+	lastIndex uint64
+	meta      Header
+	header    [frameHeaderSize]byte // scratch space of frame header
+	index     sparseIndex
+	sealed    bool  // sidecar index file is written
+	batching  bool  // the last frame is a record of batch
+	scanned   int64 // end of frames read by the last replay
+
+	writebackBytes int64
+	writeback      int64 // records before it are synced or being written back
+
+	writer writer        // buffer or direct
+	direct *directWriter // nil if file isn't opened with O_DIRECT
 }
 
 // RestoreFile open record file and restore records, push to consumer.
 func RestoreFile(filename string, at uint64, consumer Consumer) (*File, error) {
-	fd, err := file.OpenFile(filename, os.O_RDWR, 0777)
+	rf, err := OpenFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	if err = fd.Lock(); err != nil {
-		fd.Close()
+	if err = rf.Restore(at, consumer); err != nil {
+		rf.file.Unlock()
+		rf.file.Close()
 		return nil, err
 	}
+	return rf, nil
+}
 
-	buffer := file.BufferCreate(fd)
+// OpenFile open record file and verify its header, records
+// should be restored by Restore before any write.
+func OpenFile(filename string) (*File, error) {
+	return OpenFileWithOptions(filename, DefaultOptions())
+}
 
-	var offset uint32
-	if offset, err = readAllRecords(buffer, at, consumer); err != nil {
-		fd.Unlock()
-		fd.Close()
+// OpenFileWithOptions same as OpenFile, but with opts.
+func OpenFileWithOptions(filename string, opts *Options) (*File, error) {
+	fd, err := file.OpenFile(filename, os.O_RDWR, opts.Perm)
+	if err != nil {
 		return nil, err
 	}
 
-	//because buffer not support peek() method, so when read
-	// length equals to zero, we think file records is all read,
-	// but pointer of fd is over 4 byte, so we need load this
-	// file and set append mode.
-	if _, err = fd.Seek(int64(offset), io.SeekStart); err != nil {
-		fd.Unlock()
+	if err = fd.Lock(); err != nil {
 		fd.Close()
 		return nil, err
 	}
 
+	buffer := file.BufferCreateSize(fd, opts.BufferSize)
+
 	record := &File{
 		filename: filename,
 		file:     fd,
 		buffer:   buffer,
-		size:     recordFileSize,
-		offset:   offset,
+		size:     opts.Size,
+		perm:     opts.Perm,
+		offset:   headerSize,
 	}
+	record.index.setInterval(opts.IndexInterval)
+	record.writebackBytes = opts.WritebackBytes
+	record.writer = buffer
+
+	if err = record.readHeader(); err == nil {
+		err = record.openDirect(opts)
+	}
+	if err != nil {
+		fd.Unlock()
+		fd.Close()
+		return nil, fmt.Errorf("open %s: %w", filename, err)
+	}
+	record.lastIndex = record.meta.Index - 1
 
 	return record, nil
 }
 
-// CreateFile create record file with given filename.
-func CreateFile(filename string) (*File, error) {
-	fd, err := file.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0777)
+// Restore read all records and push to consumer if
+// it's index great than or equals to at. Records of batch
+// are pushed only if the batch is committed in this file.
+// If corrupted records found, it stops at the end of the
+// last valid record and returns error, see IsCorrupted
+// and Truncate.
+func (rf *File) Restore(at uint64, consumer Consumer) error {
+	assembler := NewAssembler(func(index uint64, data []byte) error {
+		if index >= at {
+			return consumer(index, data)
+		}
+		return nil
+	})
+	return rf.Replay(assembler.Visit)
+}
+
+// RestoreSkipCorrupted same as Restore, but skips corrupted records
+// if the bounds of them are known, and tell skipped the offset and
+// length of them. It still stops and returns error when the rest of
+// file could not be read, or the file ends with corrupted records.
+func (rf *File) RestoreSkipCorrupted(at uint64, consumer Consumer,
+	skipped func(offset, length int64, err error)) error {
+	assembler := NewAssembler(func(index uint64, data []byte) error {
+		if index >= at {
+			return consumer(index, data)
+		}
+		return nil
+	})
+	return rf.ReplaySkipCorrupted(assembler.Visit, skipped)
+}
+
+// Replay read all records and pass them to visitor, it is
+// the low level version of Restore, batches aren't assembled.
+func (rf *File) Replay(visitor Visitor) error {
+	return rf.replay(visitor, nil)
+}
+
+// ReplaySkipCorrupted same as Replay, but skips corrupted records
+// like RestoreSkipCorrupted.
+func (rf *File) ReplaySkipCorrupted(visitor Visitor, skipped func(offset, length int64, err error)) error {
+	return rf.replay(visitor, skipped)
+}
+
+func (rf *File) replay(visitor Visitor, skipped func(offset, length int64, err error)) error {
+	visit := func(record *Record) error {
+		rf.lastIndex = record.Index
+		rf.indexFrame(record.Type, record.Index, record.Offset)
+		return visitor(record)
+	}
+
+	stat, err := rf.file.Stat()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	if err = fd.Truncate(recordFileSize); err != nil {
-		fd.Close()
-		return nil, err
+	offset, scanned, err := readAllRecords(rf.buffer, rf.offset, stat.Size(), visit, skipped)
+	rf.offset, rf.scanned = offset, scanned
+	if IsCorrupted(err) {
+		return &CorruptionError{File: rf.filename, Offset: offset, Index: rf.lastIndex + 1, Err: err}
+	} else if err != nil {
+		return err
 	}
+	return rf.seekToEnd()
+}
 
-	if err = fd.Lock(); err != nil {
-		fd.Close()
+// Rewind flush buffer and move to the first record, so records
+// of file could be replayed again by Replay.
+func (rf *File) Rewind() error {
+	if err := rf.writer.Flush(); err != nil {
+		return err
+	}
+	rf.offset = headerSize
+	return rf.seekToEnd()
+}
+
+// TruncateAt discards all bytes after offset, which must be the
+// end of a valid record, lastIndex is the index of that record.
+func (rf *File) TruncateAt(offset int64, lastIndex uint64) error {
+	if err := rf.Sync(); err != nil {
+		return err
+	}
+	rf.offset = offset
+	rf.lastIndex = lastIndex
+	return rf.Truncate()
+}
+
+// Truncate discards all bytes after the last valid record
+// found by Restore, the space of file are preallocated again,
+// so it could be appended as usual.
+func (rf *File) Truncate() error {
+	rf.index.truncate(rf.offset)
+	if err := removeIndexFile(rf.filename); err != nil {
+		return err
+	}
+	rf.sealed = false
+	// the last kept frame may be inside batch.
+	rf.batching = true
+	if err := rf.file.Truncate(rf.offset); err != nil {
+		return err
+	}
+	if rf.offset < rf.size {
+		if err := file.Preallocate(rf.file.File, rf.size); err != nil {
+			return err
+		}
+	}
+	if err := rf.file.Sync(); err != nil {
+		return err
+	}
+	return rf.seekToEnd()
+}
+
+// Offset returns the end of the last valid record in file.
+func (rf *File) Offset() int64 {
+	return atomic.LoadInt64(&rf.offset)
+}
+
+// Scanned returns the end of frames read by the last replay, it's
+// after Offset if the corrupted frames with known length are read.
+func (rf *File) Scanned() int64 {
+	return rf.scanned
+}
+
+// CreateFile create record file with given filename, sequence
+// and index of first record are saved in header of file.
+func CreateFile(filename string, seq, index uint64) (*File, error) {
+	return CreateFileWithOptions(filename, seq, index, DefaultOptions())
+}
+
+// CreateFileWithOptions same as CreateFile, but with opts. The file
+// is preallocated at filename with suffix ".tmp" and renamed
+// after its header is written, so a crash never leaves a record file
+// without valid header.
+func CreateFileWithOptions(filename string, seq, index uint64, opts *Options) (*File, error) {
+	tmpname := filename + tmpFileSuffix
+	if err := Preallocate(tmpname, opts); err != nil {
+		os.Remove(tmpname)
 		return nil, err
 	}
+	rf, err := CreateFileFrom(tmpname, filename, seq, index, opts)
+	if err != nil {
+		os.Remove(tmpname)
+		return nil, err
+	}
+	return rf, nil
+}
 
-	buffer := file.BufferCreate(fd)
+// Preallocate create file at filename with the space of record file,
+// it could be turned into record file by CreateFileFrom later.
+func Preallocate(filename string, opts *Options) error {
+	fd, err := os.OpenFile(filename, os.O_CREATE|os.O_TRUNC|os.O_RDWR, opts.Perm)
+	if err != nil {
+		return err
+	}
+	if err = file.Preallocate(fd, opts.Size); err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
 
-	record := &File{
-		filename: filename,
-		file:     fd,
-		buffer:   buffer,
-		size:     recordFileSize,
-		offset:   0,
+// CreateFileFrom same as CreateFileWithOptions, but reuses the file
+// preallocated at tmpname by Preallocate, it's renamed to filename.
+// The header is written before renaming, so the file at filename
+// always has a valid header.
+func CreateFileFrom(tmpname, filename string, seq, index uint64, opts *Options) (*File, error) {
+	meta := Header{
+		Version:  formatVersion,
+		Sequence: seq,
+		Index:    index,
+		Created:  time.Now(),
+	}
+	if err := writeHeaderTo(tmpname, &meta); err != nil {
+		return nil, err
+	}
+	// the sidecar of stale file with same name.
+	if err := removeIndexFile(filename); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpname, filename); err != nil {
+		return nil, err
 	}
 
-	return record, nil
+	rf, err := OpenFileWithOptions(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = rf.seekToEnd(); err != nil {
+		rf.Close()
+		return nil, err
+	}
+	return rf, nil
+}
+
+// Header returns header of record file.
+func (rf *File) Header() Header {
+	return rf.meta
+}
+
+// LastIndex returns index of the last record, or index of
+// the first record minus one if there no any records.
+func (rf *File) LastIndex() uint64 {
+	return rf.lastIndex
 }
 
 // Close unlock and close current file,
 // if current file not sync, call sync.
 func (rf *File) Close() error {
-	if err := rf.Sync(); err != nil {
-		return err
+	// file is always closed, even if sync fails.
+	err := rf.Sync()
+	if uerr := rf.file.Unlock(); err == nil {
+		err = uerr
 	}
-
-	if err := rf.file.Unlock(); err != nil {
-		return err
+	if cerr := rf.file.Close(); err == nil {
+		err = cerr
 	}
-
-	return rf.file.Close()
+	if rf.direct != nil {
+		if cerr := rf.direct.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
 }
 
 // Full test whether current file size great than rotate size.
 func (rf *File) Full() bool {
-	return atomic.LoadUint32(&rf.offset) >= rf.size
+	return atomic.LoadInt64(&rf.offset) >= rf.size
 }
 
+// Write append record to buffer, the record will be
+// persisted after Sync.
 func (rf *File) Write(index uint64, data []byte) error {
 	if len(data) == 0 {
-		return errEmptyRecord
+		return ErrEmptyRecord
 	}
+	return rf.writeFrame(RecordFull, index, data)
+}
 
-	recrd := record{
-		Index: index,
-		Data:  data,
+// WriteBatch append a record of batch to buffer, it will be
+// replayed only if WriteCommit is called for the batch.
+func (rf *File) WriteBatch(index uint64, data []byte) error {
+	if len(data) == 0 {
+		return ErrEmptyRecord
 	}
+	return rf.writeFrame(RecordBatch, index, data)
+}
 
-	recrd.Crc32 = getCrc32(&recrd)
-	bytes, err := pd.Marshal(&recrd)
-	if err != nil {
+// WriteCommit append the commit of batch to buffer, index is the
+// index of last record of batch, and count is the number of records.
+func (rf *File) WriteCommit(index uint64, count int) error {
+	var payload [4]byte
+	binary.LittleEndian.PutUint32(payload[:], uint32(count))
+	return rf.writeFrame(RecordCommit, index, payload[:])
+}
+
+func (rf *File) writeFrame(typ RecordType, index uint64, data []byte) error {
+	encodeFrameHeader(rf.header[:], typ, index, data)
+	if err := rf.writer.Write(rf.header[:]); err != nil {
 		return err
 	}
 
-	length := uint32(len(bytes))
-	buf := [4]byte{}
-	binary.LittleEndian.PutUint32(buf[:], length)
-	if err := rf.buffer.Write(buf[:]); err != nil {
+	if err := rf.writer.Write(data); err != nil {
 		return err
 	}
 
-	if err := rf.buffer.Write(bytes); err != nil {
+	offset := atomic.AddInt64(&rf.offset, int64(frameHeaderSize+len(data)))
+	rf.indexFrame(typ, index, offset-int64(frameHeaderSize+len(data)))
+	rf.lastIndex = index
+
+	if rf.writebackBytes > 0 && offset-rf.writeback >= rf.writebackBytes {
+		return rf.startWriteback(offset)
+	}
+	return nil
+}
+
+// startWriteback flush buffer and start writeback of the records
+// before offset in background.
+func (rf *File) startWriteback(offset int64) error {
+	if err := rf.writer.Flush(); err != nil {
 		return err
 	}
+	if err := file.StartWriteback(rf.file.File, rf.writeback, offset-rf.writeback); err != nil {
+		return err
+	}
+	rf.writeback = offset
+	return nil
+}
 
-	atomic.AddUint32(&rf.offset, length+4)
+// indexFrame add the frame at offset to sparse index. Only the full
+// records and the first records of batches are indexed, so seeking
+// by index never starts inside a batch, or at the commit of batch,
+// whose index is the last record of batch.
+func (rf *File) indexFrame(typ RecordType, index uint64, offset int64) {
+	if typ == RecordFull || (typ == RecordBatch && !rf.batching) {
+		rf.index.add(index, offset)
+	}
+	rf.batching = typ == RecordBatch
+}
 
+// Seal write the sidecar index file, it should be called when
+// all records are synced and no more records will be written.
+func (rf *File) Seal() error {
+	if rf.sealed {
+		return nil
+	}
+	if err := writeIndexFile(rf.filename, &rf.index, rf.perm); err != nil {
+		return err
+	}
+	rf.sealed = true
 	return nil
 }
 
-// Sync flush buffer, ans sync file.
+// SeekIndex move to the record near before the one at index by sparse
+// index, so Replay could start at there instead of the first record.
+// The sidecar index file is loaded if the file is just opened. It does
+// nothing if no entries could be used.
+func (rf *File) SeekIndex(index uint64) error {
+	if rf.index.len() == 0 {
+		entries, err := readIndexFile(rf.filename)
+		if err != nil {
+			// rebuilt by Replay.
+			return nil
+		}
+		rf.index.reset(entries)
+		rf.sealed = true
+	}
+
+	entry, ok := rf.index.lookup(index)
+	if !ok {
+		return nil
+	}
+	rf.offset = entry.offset
+	rf.lastIndex = entry.index - 1
+	return rf.seekToEnd()
+}
+
+// Lookup returns offset of the record near before the one at index
+// by sparse index, it's safe to call it during writing.
+func (rf *File) Lookup(index uint64) (int64, bool) {
+	entry, ok := rf.index.lookup(index)
+	return entry.offset, ok
+}
+
+// Flush write buffered records to file without sync, so they
+// are visible to Reader.
+func (rf *File) Flush() error {
+	return rf.writer.Flush()
+}
+
+// Sync flush buffer, and sync data of file. The metadata needn't
+// be synced, since the space of file is preallocated.
 func (rf *File) Sync() error {
-	if err := rf.buffer.Flush(); err != nil {
+	if err := rf.writer.Flush(); err != nil {
+		return err
+	}
+
+	if err := file.Fdatasync(rf.file.File); err != nil {
+		return err
+	}
+	rf.writeback = atomic.LoadInt64(&rf.offset)
+	return nil
+}
+
+// writeHeaderTo write header to the file at filename, and sync it.
+func writeHeaderTo(filename string, header *Header) error {
+	fd, err := os.OpenFile(filename, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	buf := [headerSize]byte{}
+	encodeHeader(buf[:], header)
+	if _, err = fd.WriteAt(buf[:], 0); err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (rf *File) readHeader() error {
+	buf := [headerSize]byte{}
+	if err := rf.buffer.Read(buf[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrBadHeader
+		}
 		return err
 	}
+	return decodeHeader(buf[:], &rf.meta)
+}
+
+// seekToEnd set append mode. Because buffer not support peek()
+// method, so when read length equals to zero, we think file
+// records is all read, but pointer of fd is over the end of
+// records, so we need seek to the end of records.
+func (rf *File) seekToEnd() error {
+	rf.buffer.Reset()
+	rf.writeback = rf.offset
+	if _, err := rf.file.Seek(rf.offset, io.SeekStart); err != nil {
+		return err
+	}
+	if rf.direct != nil {
+		return rf.direct.reset(rf.offset)
+	}
+	return nil
+}
 
-	return rf.file.Sync()
+// openDirect open file again with O_DIRECT for writing records if
+// it's required by opts, the records are still read by buffer.
+func (rf *File) openDirect(opts *Options) error {
+	if !opts.Direct {
+		return nil
+	}
+	direct, err := newDirectWriter(rf.filename, opts.BufferSize, rf.perm)
+	if err != nil {
+		return err
+	}
+	if err = direct.reset(rf.offset); err != nil {
+		direct.Close()
+		return err
+	}
+	// the records are written back by O_DIRECT already.
+	rf.writebackBytes = 0
+	rf.writer, rf.direct = direct, direct
+	return nil
 }
 
-func readAllRecords(reader *file.Buffer, at uint64, consumer Consumer) (uint32, error) {
-	var eat uint32
+// readAllRecords read records from offset until there no more
+// records, end is the size of file. It returns the end of valid
+// records, and the end of frames read includes the corrupted ones
+// with known length. If skipped isn't nil, corrupted records with
+// known length are skipped, if the last one of records is skipped,
+// the error of it will be returned.
+func readAllRecords(reader *file.Buffer, offset, end int64, visitor Visitor,
+	skipped func(offset, length int64, err error)) (int64, int64, error) {
+	valid := offset
+	var skipErr error
+	header := [frameHeaderSize]byte{}
 	for {
-		length, recrd, err := readRecord(reader)
+		length, record, err := readRecord(reader, header[:], end-offset)
 		if err != nil {
-			if err == io.EOF {
-				return 0, errUnexpectedEOF
+			if skipped == nil || length == 0 || !IsCorrupted(err) {
+				return valid, offset + length, err
 			}
-			return 0, err
+			skipped(offset, length, err)
+			skipErr = err
+			offset += length
+			continue
 		}
 
 		if length == 0 {
@@ -186,48 +602,58 @@ func readAllRecords(reader *file.Buffer, at uint64, consumer Consumer) (uint32,
 			break
 		}
 
-		wchecksum := getCrc32(&recrd)
-		if wchecksum != recrd.Crc32 {
-			return 0, errBadChecksum
-		}
-
-		if recrd.Index >= at {
-			if err := consumer(recrd.Index, recrd.Data); err != nil {
-				return 0, err
-			}
+		record.Offset = offset
+		if err := visitor(&record); err != nil {
+			return valid, offset, err
 		}
-		eat += length + 4
+		offset += length
+		valid, skipErr = offset, nil
 	}
-	return eat, nil
+	return valid, offset, skipErr
 }
 
-func readRecord(reader *file.Buffer) (length uint32, record record, err error) {
-	uint32buf := [4]byte{}
-	err = reader.Read(uint32buf[:])
+// readRecord read a frame from reader, and returns the size of
+// frame. A zero length means there no more records. If frame is
+// read but it's corrupted, the size of frame is returned too.
+func readRecord(reader *file.Buffer, header []byte, limit int64) (length int64, record Record, err error) {
+	err = reader.Read(header)
 	if err == io.EOF {
 		err = nil
 		return
+	} else if err == io.ErrUnexpectedEOF {
+		err = ErrUnexpectedEOF
+		return
+	}
+	if err != nil || isZeroFrameHeader(header) {
+		return
 	}
 
-	length = binary.LittleEndian.Uint32(uint32buf[:])
-	if length == 0 {
+	size, crc, index, typ := decodeFrameHeader(header)
+	if int64(size)+frameHeaderSize > limit {
+		err = ErrUnexpectedEOF
 		return
 	}
 
-	bytes := make([]byte, length)
-	if err = reader.Read(bytes); err != nil {
+	data := make([]byte, size)
+	if err = reader.Read(data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrUnexpectedEOF
+		}
 		return
 	}
 
-	err = pd.Unmarshal(&record, bytes)
+	length = frameHeaderSize + int64(size)
+	if frameChecksum(header, data) != crc {
+		err = ErrBadChecksum
+		return
+	}
+	if !typ.isValid() {
+		err = ErrBadRecordType
+		return
+	}
 
+	record.Type = typ
+	record.Index = index
+	record.Data = data
 	return
 }
-
-func getCrc32(record *record) uint32 {
-	a := [8]byte{}
-	binary.LittleEndian.PutUint64(a[:], record.Index)
-	crc := crc32.Checksum(a[:], crc32Table)
-	crc = crc32.Update(crc, crc32Table, record.Data)
-	return crc
-}
@@ -2,13 +2,14 @@ package record
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"testing"
 )
 
 func TestFile_Full(t *testing.T) {
 	filename := "/tmp/xxxxx"
-	file, err := CreateFile(filename)
+	file, err := CreateFile(filename, 0, 1)
 	if err != nil {
 		t.Error(err)
 	}
@@ -23,7 +24,7 @@ func TestFile_Full(t *testing.T) {
 		t.Errorf("want no full, but full")
 	}
 
-	data = make([]byte, 1)
+	data = make([]byte, 100-frameHeaderSize)
 	if err := file.Write(2, data); err != nil {
 		t.Error(err)
 	}
@@ -51,7 +52,7 @@ func TestFile_Restore(t *testing.T) {
 	}
 
 	filename := "/tmp/xxx"
-	file, err := CreateFile(filename)
+	file, err := CreateFile(filename, 0, 1)
 	if err != nil {
 		t.Error(err)
 		return
@@ -100,3 +101,225 @@ func TestFile_Restore(t *testing.T) {
 
 	os.Remove(filename)
 }
+
+func TestFile_RestoreTorn(t *testing.T) {
+	filename := "/tmp/xxx"
+	defer os.Remove(filename)
+
+	tests := []struct {
+		corrupt func(f *os.File, offset int64) error
+	}{
+		// payload of the last record isn't written.
+		{func(f *os.File, offset int64) error {
+			_, err := f.WriteAt(make([]byte, 10), offset+frameHeaderSize)
+			return err
+		}},
+		// header of the last record is half written.
+		{func(f *os.File, offset int64) error {
+			_, err := f.WriteAt(make([]byte, frameHeaderSize+100), offset+4)
+			return err
+		}},
+		// file is cut in the middle of the last record.
+		{func(f *os.File, offset int64) error {
+			return f.Truncate(offset + frameHeaderSize + 5)
+		}},
+		// garbage length.
+		{func(f *os.File, offset int64) error {
+			_, err := f.WriteAt([]byte{0xff, 0xff, 0xff, 0x7f}, offset)
+			return err
+		}},
+	}
+
+	for i, test := range tests {
+		file, err := CreateFile(filename, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for idx := uint64(1); idx < 10; idx++ {
+			if err = file.Write(idx, bytes.Repeat([]byte{byte(idx)}, 100)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		offset := file.Offset()
+		if err = file.Write(10, bytes.Repeat([]byte{0x10}, 100)); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		fd, err := os.OpenFile(filename, os.O_RDWR, 0777)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = test.corrupt(fd, int64(offset)); err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+
+		file, err = OpenFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		err = file.Restore(0, func(index uint64, data []byte) error {
+			count++
+			return nil
+		})
+		if !IsCorrupted(err) {
+			t.Fatalf("#%d: want corrupted, get: %v", i, err)
+		}
+		var cerr *CorruptionError
+		if !errors.As(err, &cerr) || cerr.File != filename || cerr.Offset != offset || cerr.Index != 10 {
+			t.Errorf("#%d: err = %v, want corruption of index 10 at offset %d", i, err, offset)
+		}
+		if count != 9 {
+			t.Errorf("#%d: count = %d, want 9", i, count)
+		}
+		if file.Offset() != offset {
+			t.Errorf("#%d: offset = %d, want %d", i, file.Offset(), offset)
+		}
+
+		if err = file.Truncate(); err != nil {
+			t.Fatal(err)
+		}
+		if err = file.Write(10, []byte{0x11}); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+
+		count = 0
+		file, err = RestoreFile(filename, 0, func(index uint64, data []byte) error {
+			count++
+			if index == 10 && !bytes.Equal(data, []byte{0x11}) {
+				t.Errorf("#%d: data = %v, want [0x11]", i, data)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if count != 10 {
+			t.Errorf("#%d: count = %d, want 10", i, count)
+		}
+		file.Close()
+		os.Remove(filename)
+	}
+}
+
+func TestFile_CreateFrom(t *testing.T) {
+	tmpname, filename := "/tmp/xxxxxxx.tmp", "/tmp/xxxxxxx"
+	opts := DefaultOptions()
+	opts.Size = 4096
+	if err := Preallocate(tmpname, opts); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpname)
+
+	file, err := CreateFileFrom(tmpname, filename, 3, 100, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	if _, err = os.Stat(tmpname); !os.IsNotExist(err) {
+		t.Errorf("tmp file should be renamed, err = %v", err)
+	}
+	for idx := uint64(100); idx < 110; idx++ {
+		if err = file.Write(idx, []byte{byte(idx)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	count := 0
+	file, err = RestoreFile(filename, 0, func(index uint64, data []byte) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if header := file.Header(); header.Sequence != 3 || header.Index != 100 {
+		t.Errorf("header = %+v, want sequence 3, index 100", header)
+	}
+	if count != 10 || file.LastIndex() != 109 {
+		t.Errorf("count = %d, last = %d, want 10, 109", count, file.LastIndex())
+	}
+}
+
+func TestFile_Writeback(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	opts := DefaultOptions()
+	opts.WritebackBytes = 1024
+	file, err := CreateFileWithOptions(filename, 0, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+
+	for idx := uint64(1); idx <= 10; idx++ {
+		if err = file.Write(idx, make([]byte, 200)); err != nil {
+			t.Fatal(err)
+		}
+		if file.Offset()-file.writeback >= opts.WritebackBytes {
+			t.Fatalf("writeback = %d, offset = %d, should start writeback", file.writeback, file.Offset())
+		}
+	}
+	if file.writeback == headerSize {
+		t.Errorf("writeback should be started")
+	}
+	if err = file.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if file.writeback != file.Offset() {
+		t.Errorf("writeback = %d, want %d", file.writeback, file.Offset())
+	}
+	file.Close()
+
+	count := 0
+	file, err = RestoreFile(filename, 0, func(index uint64, data []byte) error {
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if count != 10 {
+		t.Errorf("count = %d, want 10", count)
+	}
+}
+
+func TestFile_CreateOverTmp(t *testing.T) {
+	filename := "/tmp/xxxxx-create"
+	defer RemoveFile(filename)
+
+	// the file left by a crash in the middle of creating.
+	if err := Preallocate(filename+tmpFileSuffix, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	file, err := CreateFile(filename, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filename + tmpFileSuffix); !os.IsNotExist(err) {
+		t.Errorf("tmp file should be renamed, err = %v", err)
+	}
+
+	file, err = OpenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = file.Restore(1, func(uint64, []byte) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if file.LastIndex() != 1 {
+		t.Errorf("last index = %d, want 1", file.LastIndex())
+	}
+}
@@ -0,0 +1,195 @@
+package record
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+)
+
+// A sealed record file has a sidecar file named with suffix IndexFileSuffix,
+// it's a sparse index maps index of record to offset in file, all integers
+// are little endian:
+//
+//	+--------+---------+----------+-------+---------------------+--------+
+//	| magic  | version | reserved | count | (index, offset) ... | crc32c |
+//	|   4    |    2    |    2     |   4   |     16 * count      |   4    |
+//	+--------+---------+----------+-------+---------------------+--------+
+//
+// crc32c is the CRC-32 (Castagnoli) of all bytes before it.
+// See FORMAT.md for the full specification.
+const (
+	// IndexFileSuffix is the suffix of sidecar index file.
+	IndexFileSuffix = ".idx"
+
+	indexMagic        = 0x494c4157 // "WALI"
+	indexHeaderSize   = 12
+	indexEntrySize    = 16
+	indexInterval     = 64 * 1024 // default bytes of records between entries
+	indexCountOffset  = 8
+	indexEntriesStart = indexHeaderSize
+)
+
+var errBadIndexFile = errors.New("bad index file")
+
+type indexEntry struct {
+	index  uint64
+	offset int64
+}
+
+// sparseIndex has an entry per interval bytes of records.
+// It's safe for concurrent use.
+type sparseIndex struct {
+	mu       sync.RWMutex
+	interval int64
+	entries  []indexEntry
+}
+
+// setInterval set the bytes of records between entries, zero or
+// negative interval uses the default one.
+func (si *sparseIndex) setInterval(interval int64) {
+	if interval <= 0 {
+		interval = indexInterval
+	}
+	si.interval = interval
+}
+
+// add append entry of record at offset, if it's far enough from the
+// last entry. Records must be added in order of offset.
+func (si *sparseIndex) add(index uint64, offset int64) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	n := len(si.entries)
+	if n == 0 || offset-si.entries[n-1].offset >= si.interval {
+		si.entries = append(si.entries, indexEntry{index: index, offset: offset})
+	}
+}
+
+// lookup returns the last entry whose index less than or equals to index.
+func (si *sparseIndex) lookup(index uint64) (indexEntry, bool) {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	i := sort.Search(len(si.entries), func(i int) bool {
+		return si.entries[i].index > index
+	})
+	if i == 0 {
+		return indexEntry{}, false
+	}
+	return si.entries[i-1], true
+}
+
+// truncate discards entries at or after offset.
+func (si *sparseIndex) truncate(offset int64) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+
+	i := sort.Search(len(si.entries), func(i int) bool {
+		return si.entries[i].offset >= offset
+	})
+	si.entries = si.entries[:i]
+}
+
+func (si *sparseIndex) reset(entries []indexEntry) {
+	si.mu.Lock()
+	defer si.mu.Unlock()
+	si.entries = entries
+}
+
+func (si *sparseIndex) len() int {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	return len(si.entries)
+}
+
+func (si *sparseIndex) encode() []byte {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+
+	size := indexHeaderSize + indexEntrySize*len(si.entries)
+	buf := make([]byte, size+4)
+	binary.LittleEndian.PutUint32(buf[0:], indexMagic)
+	binary.LittleEndian.PutUint16(buf[4:], formatVersion)
+	binary.LittleEndian.PutUint32(buf[indexCountOffset:], uint32(len(si.entries)))
+	for i, entry := range si.entries {
+		pos := indexEntriesStart + i*indexEntrySize
+		binary.LittleEndian.PutUint64(buf[pos:], entry.index)
+		binary.LittleEndian.PutUint64(buf[pos+8:], uint64(entry.offset))
+	}
+	binary.LittleEndian.PutUint32(buf[size:], crc32.Checksum(buf[:size], crc32Table))
+	return buf
+}
+
+func decodeIndex(buf []byte) ([]indexEntry, error) {
+	if len(buf) < indexHeaderSize+4 ||
+		binary.LittleEndian.Uint32(buf[0:]) != indexMagic ||
+		binary.LittleEndian.Uint16(buf[4:]) != formatVersion {
+		return nil, errBadIndexFile
+	}
+	count := int(binary.LittleEndian.Uint32(buf[indexCountOffset:]))
+	size := indexHeaderSize + indexEntrySize*count
+	if len(buf) != size+4 ||
+		binary.LittleEndian.Uint32(buf[size:]) != crc32.Checksum(buf[:size], crc32Table) {
+		return nil, errBadIndexFile
+	}
+
+	entries := make([]indexEntry, 0, count)
+	for i := 0; i < count; i++ {
+		pos := indexEntriesStart + i*indexEntrySize
+		entries = append(entries, indexEntry{
+			index:  binary.LittleEndian.Uint64(buf[pos:]),
+			offset: int64(binary.LittleEndian.Uint64(buf[pos+8:])),
+		})
+	}
+	return entries, nil
+}
+
+func indexFileName(filename string) string {
+	return filename + IndexFileSuffix
+}
+
+// readIndexFile returns entries of sidecar index file of filename.
+func readIndexFile(filename string) ([]indexEntry, error) {
+	buf, err := ioutil.ReadFile(indexFileName(filename))
+	if err != nil {
+		return nil, err
+	}
+	return decodeIndex(buf)
+}
+
+// writeIndexFile write sidecar index file of filename and sync it.
+func writeIndexFile(filename string, si *sparseIndex, perm os.FileMode) error {
+	fd, err := os.OpenFile(indexFileName(filename), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = fd.Write(si.encode()); err == nil {
+		err = fd.Sync()
+	}
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// RemoveFile removes record file and its sidecar index file.
+// The sidecar is removed at first, so it never describes another
+// file created with the same name.
+func RemoveFile(filename string) error {
+	if err := removeIndexFile(filename); err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+func removeIndexFile(filename string) error {
+	err := os.Remove(indexFileName(filename))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package record
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSparseIndex_Lookup(t *testing.T) {
+	si := sparseIndex{interval: 100}
+	for i := 0; i < 100; i++ {
+		si.add(uint64(i+1), headerSize+int64(i*30))
+	}
+	// one entry per 4 records.
+	if si.len() != 25 {
+		t.Fatalf("len = %d, want 25", si.len())
+	}
+
+	tests := []struct {
+		index  uint64
+		want   indexEntry
+		wantOk bool
+	}{
+		{0, indexEntry{}, false},
+		{1, indexEntry{1, headerSize}, true},
+		{4, indexEntry{1, headerSize}, true},
+		{5, indexEntry{5, headerSize + 120}, true},
+		{1000, indexEntry{97, headerSize + 96*30}, true},
+	}
+	for i, test := range tests {
+		entry, ok := si.lookup(test.index)
+		if entry != test.want || ok != test.wantOk {
+			t.Errorf("#%d: lookup = %v, %v, want %v, %v", i, entry, ok, test.want, test.wantOk)
+		}
+	}
+
+	si.truncate(headerSize + 120)
+	if entry, _ := si.lookup(1000); entry.index != 1 {
+		t.Errorf("lookup after truncate = %v, want index 1", entry)
+	}
+}
+
+func TestSparseIndex_EncodeDecode(t *testing.T) {
+	si := sparseIndex{interval: 1}
+	for i := 0; i < 10; i++ {
+		si.add(uint64(i*10), int64(i*100))
+	}
+
+	buf := si.encode()
+	entries, err := decodeIndex(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, si.entries) {
+		t.Errorf("entries = %v, want %v", entries, si.entries)
+	}
+
+	buf[indexEntriesStart] ^= 0x1
+	if _, err = decodeIndex(buf); err != errBadIndexFile {
+		t.Errorf("err = %v, want %v", err, errBadIndexFile)
+	}
+	if _, err = decodeIndex(buf[:8]); err != errBadIndexFile {
+		t.Errorf("err = %v, want %v", err, errBadIndexFile)
+	}
+}
+
+func TestFile_SeekIndex(t *testing.T) {
+	filename := "/tmp/xxxxxxx"
+	opts := DefaultOptions()
+	opts.IndexInterval = 1024
+	file, err := CreateFileWithOptions(filename, 0, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveFile(filename)
+
+	for i := 1; i <= 100; i++ {
+		if err = file.Write(uint64(i), make([]byte, 100)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = file.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(indexFileName(filename)); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err = OpenFileWithOptions(filename, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err = file.SeekIndex(50); err != nil {
+		t.Fatal(err)
+	}
+
+	var first uint64
+	err = file.Replay(func(record *Record) error {
+		if first == 0 {
+			first = record.Index
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first <= 1 || first > 50 {
+		t.Errorf("first = %d, want in (1, 50]", first)
+	}
+	if file.LastIndex() != 100 {
+		t.Errorf("last index = %d, want 100", file.LastIndex())
+	}
+
+	// the sidecar is stale after truncate.
+	if err = file.TruncateAt(headerSize, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(indexFileName(filename)); !os.IsNotExist(err) {
+		t.Errorf("sidecar exists after truncate: %v", err)
+	}
+}
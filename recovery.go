@@ -0,0 +1,257 @@
+package wal
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+// RecoveryMode decides how to deal with corrupted records when open wal.
+type RecoveryMode int
+
+const (
+	// TolerateCorruptedTailRecords tolerates torn or corrupted records
+	// at the end of the last wal file, such as the process crashed in
+	// the middle of writing, they are truncated. Corruption in other
+	// files is a hard error.
+	TolerateCorruptedTailRecords RecoveryMode = iota
+	// AbsoluteConsistency treats any corrupted records as hard error.
+	AbsoluteConsistency
+	// PointInTimeRecovery stops replay at the first corrupted record,
+	// the records after it and the following files are discarded, and
+	// the wal is appended after the last valid record.
+	PointInTimeRecovery
+	// SkipAnyCorruptedRecords skips corrupted records and logs them,
+	// if the rest of a file couldn't be read, they are skipped too.
+	SkipAnyCorruptedRecords
+)
+
+func (mode RecoveryMode) String() string {
+	switch mode {
+	case TolerateCorruptedTailRecords:
+		return "TolerateCorruptedTailRecords"
+	case AbsoluteConsistency:
+		return "AbsoluteConsistency"
+	case PointInTimeRecovery:
+		return "PointInTimeRecovery"
+	case SkipAnyCorruptedRecords:
+		return "SkipAnyCorruptedRecords"
+	default:
+		return "UnknownRecoveryMode"
+	}
+}
+
+// batchStart is the position of the first record of batch
+// which wait for commit.
+type batchStart struct {
+	file      int    // position in recovered files
+	offset    int64  // offset in file
+	lastIndex uint64 // index of the record before batch
+}
+
+// recoverFiles restore records from files in names, and returns
+// them. The corrupted records are processed according to mode.
+// Records of batch without commit are truncated at the end.
+func recoverFiles(dir string, names []string, lsn uint64, consumer record.Consumer,
+	opts *Options, report *RecoveryReport) ([]*recordFile, error) {
+	mode, logger := opts.RecoveryMode, opts.Logger
+	recordFiles := make([]*recordFile, 0)
+
+	var segment *SegmentReport
+	assembler := record.NewAssembler(func(index uint64, data []byte) error {
+		segment.Records++
+		report.consume(index, lsn)
+		if index < lsn {
+			return nil
+		}
+		return consumer(index, data)
+	})
+
+	var batch batchStart
+	for i := 0; i < len(names); i++ {
+		path := filepath.Join(dir, names[i])
+		seq, idx := mustParseWalName(names[i])
+		tail := i == len(names)-1
+
+		f, err := openFile(path, seq, idx, opts)
+		if err != nil {
+			closeAll(recordFiles)
+			return nil, err
+		}
+
+		segment = &SegmentReport{
+			File:       path,
+			Sequence:   seq,
+			FirstIndex: idx,
+		}
+		if i == 0 && lsn > idx {
+			// skip the records before lsn.
+			if err = f.SeekIndex(lsn); err != nil {
+				f.Close()
+				closeAll(recordFiles)
+				return nil, err
+			}
+		}
+
+		lastIndex := f.LastIndex()
+		visit := func(r *record.Record) error {
+			if r.Type == record.RecordBatch && len(assembler.Pending()) == 0 {
+				batch = batchStart{file: len(recordFiles), offset: r.Offset, lastIndex: lastIndex}
+			}
+			if r.Type != record.RecordBatch {
+				lastIndex = r.Index
+			}
+			return assembler.Visit(r)
+		}
+
+		stop := false
+		err = replayFile(path, f, visit, opts, report)
+		if record.IsCorrupted(err) {
+			cause, offset, length := err, f.Offset(), f.Scanned()-f.Offset()
+			switch {
+			case mode == PointInTimeRecovery:
+				logger.Warnf("stop replay at offset %d of %s: %v", offset, path, cause)
+				if err = discardFiles(dir, names[i+1:], logger, report); err == nil {
+					report.repair(RepairTruncated, path, offset, length, cause)
+					err = f.Truncate()
+				}
+				stop = true
+			case tail && mode != AbsoluteConsistency:
+				logger.Warnf("truncate torn tail of %s at offset %d: %v", path, offset, cause)
+				report.repair(RepairTruncated, path, offset, length, cause)
+				err = f.Truncate()
+			case mode == SkipAnyCorruptedRecords:
+				logger.Warnf("skip the rest of %s from offset %d: %v", path, offset, cause)
+				report.repair(RepairSkipped, path, offset, length, cause)
+				err = nil
+			}
+		}
+		if err == nil && !tail && !stop {
+			// rebuild the sidecar index file if it's missing.
+			err = f.Seal()
+		}
+		if err != nil {
+			f.Close()
+			closeAll(recordFiles)
+			return nil, err
+		}
+
+		segment.LastIndex = f.LastIndex()
+		segment.Bytes = int64(f.Offset())
+		report.Segments = append(report.Segments, *segment)
+		report.Bytes += segment.Bytes
+
+		recordFile := makeRecordFile(path, seq, idx, f)
+		recordFile.lastIndex = f.LastIndex()
+		recordFiles = append(recordFiles, recordFile)
+		if stop {
+			break
+		}
+	}
+
+	if len(assembler.Pending()) > 0 {
+		var err error
+		if recordFiles, err = truncateBatch(recordFiles, batch, logger, report); err != nil {
+			closeAll(recordFiles)
+			return nil, err
+		}
+	}
+
+	// the replayed records may not be synced before crash.
+	if err := recordFiles[len(recordFiles)-1].file.Sync(); err != nil {
+		closeAll(recordFiles)
+		return nil, err
+	}
+	return recordFiles, nil
+}
+
+// truncateBatch truncate the records of batch without commit, the
+// files after the one batch starts at have only records of batch,
+// so they are discarded.
+func truncateBatch(recordFiles []*recordFile, batch batchStart,
+	logger Logger, report *RecoveryReport) ([]*recordFile, error) {
+	for _, rf := range recordFiles[batch.file+1:] {
+		logger.Warnf("discard wal file %s, it has uncommitted batch only", rf.filename)
+		report.repair(RepairDiscarded, rf.filename, 0, rf.file.Offset(), nil)
+		if err := rf.file.Close(); err != nil {
+			return recordFiles, err
+		}
+		if err := record.RemoveFile(rf.filename); err != nil {
+			return recordFiles[:batch.file+1], err
+		}
+	}
+	recordFiles = recordFiles[:batch.file+1]
+
+	rf := recordFiles[batch.file]
+	logger.Warnf("truncate uncommitted batch of %s at offset %d", rf.filename, batch.offset)
+	report.repair(RepairTruncated, rf.filename, batch.offset, rf.file.Offset()-batch.offset, nil)
+	if err := rf.file.TruncateAt(batch.offset, batch.lastIndex); err != nil {
+		return recordFiles, err
+	}
+	rf.lastIndex = batch.lastIndex
+
+	for _, segment := range report.Segments[batch.file+1:] {
+		report.Bytes -= segment.Bytes
+	}
+	report.Segments = report.Segments[:batch.file+1]
+	segment := &report.Segments[batch.file]
+	report.Bytes -= segment.Bytes - batch.offset
+	segment.LastIndex = batch.lastIndex
+	segment.Bytes = batch.offset
+	return recordFiles, nil
+}
+
+// openFile open record file, and ensure the header of file matchs
+// with the sequence and index in filename.
+func openFile(filename string, seq, idx uint64, opts *Options) (*record.File, error) {
+	file, err := record.OpenFileWithOptions(filename, opts.recordOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	header := file.Header()
+	if header.Sequence != seq || header.Index != idx {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", filename, ErrHeaderMismatch)
+	}
+	return file, nil
+}
+
+func replayFile(filename string, file *record.File, visitor record.Visitor,
+	opts *Options, report *RecoveryReport) error {
+	if opts.RecoveryMode != SkipAnyCorruptedRecords {
+		return file.Replay(visitor)
+	}
+	return file.ReplaySkipCorrupted(visitor, func(offset, length int64, err error) {
+		opts.Logger.Warnf("skip corrupted record of %s at offset %d: %v", filename, offset, err)
+		report.repair(RepairSkipped, filename, offset, length, err)
+	})
+}
+
+// discardFiles remove files which follow the point of recovery.
+func discardFiles(dir string, names []string, logger Logger, report *RecoveryReport) error {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		logger.Warnf("discard wal file %s", path)
+		report.repair(RepairDiscarded, path, 0, scanFile(path), nil)
+		if err := record.RemoveFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanFile returns the end of valid records of file, or zero if it
+// couldn't be read.
+func scanFile(filename string) int64 {
+	r, err := record.OpenReader(filename)
+	if err != nil {
+		return 0
+	}
+	defer r.Close()
+	var rec record.Record
+	for r.Next(&rec) == nil {
+	}
+	return r.Offset()
+}
@@ -0,0 +1,162 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thinkermao/wal-go/file"
+	"github.com/thinkermao/wal-go/record"
+)
+
+// createCorruptedWal create three wal files with records in [0, 30),
+// and corrupt the checksum of record 15.
+func createCorruptedWal(t *testing.T) string {
+	dir := createTmpDir(t)
+	createFileWithRecords(t, dir, 0, 0, 10)
+	offsets := createFileWithRecords(t, dir, 1, 10, 10)
+	createFileWithRecords(t, dir, 2, 20, 10)
+	corruptFile(t, filepath.Join(dir, walName(1, 10)), offsets[5])
+	return dir
+}
+
+func openAndCollect(dir string, mode RecoveryMode) (*Wal, []uint64, error) {
+	indexes := make([]uint64, 0)
+	opts := DefaultOptions()
+	opts.RecoveryMode = mode
+	wal, err := OpenWithOptions(dir, 0, func(index uint64, data []byte) error {
+		indexes = append(indexes, index)
+		return nil
+	}, opts)
+	return wal, indexes, err
+}
+
+func expectIndexes(t *testing.T, get []uint64, skip map[uint64]bool, end uint64) {
+	var want uint64
+	for _, idx := range get {
+		for skip[want] {
+			want++
+		}
+		if idx != want {
+			t.Fatalf("index = %d, want %d", idx, want)
+		}
+		want++
+	}
+	if want != end {
+		t.Fatalf("replay stop at %d, want %d", want, end)
+	}
+}
+
+func TestRecoveryMode_Corrupted(t *testing.T) {
+	modes := []RecoveryMode{AbsoluteConsistency, TolerateCorruptedTailRecords}
+	for _, mode := range modes {
+		dir := createCorruptedWal(t)
+		if _, _, err := openAndCollect(dir, mode); !record.IsCorrupted(err) {
+			t.Errorf("%v: want corrupted, get: %v", mode, err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRecoveryMode_AbsoluteConsistencyTail(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	offsets := createFileWithRecords(t, dir, 0, 0, 10)
+	corruptFile(t, filepath.Join(dir, walName(0, 0)), offsets[9])
+
+	if _, _, err := openAndCollect(dir, AbsoluteConsistency); !record.IsCorrupted(err) {
+		t.Errorf("want corrupted, get: %v", err)
+	}
+}
+
+func TestRecoveryMode_PointInTime(t *testing.T) {
+	dir := createCorruptedWal(t)
+	defer os.RemoveAll(dir)
+
+	wal, indexes, err := openAndCollect(dir, PointInTimeRecovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIndexes(t, indexes, nil, 15)
+	if file.IsExists(filepath.Join(dir, walName(2, 20))) {
+		t.Errorf("following file should be discarded")
+	}
+
+	if err = <-wal.Write(15, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, indexes, err = openAndCollect(dir, AbsoluteConsistency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIndexes(t, indexes, nil, 16)
+	wal.Close()
+}
+
+func TestRecoveryMode_SkipAnyCorrupted(t *testing.T) {
+	dir := createCorruptedWal(t)
+	defer os.RemoveAll(dir)
+
+	wal, indexes, err := openAndCollect(dir, SkipAnyCorruptedRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIndexes(t, indexes, map[uint64]bool{15: true}, 30)
+	wal.Close()
+}
+
+func TestRecoveryMode_SkipCorruptedTail(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	offsets := createFileWithRecords(t, dir, 0, 0, 10)
+	corruptFile(t, filepath.Join(dir, walName(0, 0)), offsets[9])
+
+	wal, indexes, err := openAndCollect(dir, SkipAnyCorruptedRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIndexes(t, indexes, nil, 9)
+	if err = <-wal.Write(9, []byte{0x1, 0x2}); err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+
+	// the corrupted tail must be truncated, rather than be overwritten.
+	wal, indexes, err = openAndCollect(dir, AbsoluteConsistency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectIndexes(t, indexes, nil, 10)
+	wal.Close()
+}
+
+func TestRecovery_CrashCreating(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+	createFileWithRecords(t, dir, 0, 0, 10)
+
+	// crash before the header of the next file is written.
+	tmpname := filepath.Join(dir, walName(1, 10)) + ".tmp"
+	if err := record.Preallocate(tmpname, record.DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, indexes, err := openAndCollect(dir, TolerateCorruptedTailRecords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	expectIndexes(t, indexes, nil, 10)
+	if file.IsExists(tmpname) {
+		t.Errorf("file %s should be removed", tmpname)
+	}
+	if err = <-wal.Write(10, []byte{0xa}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package wal
+
+import (
+	"os"
+	"testing"
+)
+
+// TestReleaseBefore writes records in [0, 30) to three files, the
+// first two of them have 13 records.
+func TestReleaseBefore(t *testing.T) {
+	tests := []struct {
+		index uint64
+		files int
+		first uint64
+	}{
+		{0, 3, 0},
+		{12, 3, 0},
+		{13, 2, 13},
+		{25, 2, 13},
+		{26, 1, 26},
+		{100, 1, 26}, // the last file is never released
+	}
+	for i, test := range tests {
+		dir := createTmpDir(t)
+		opts := &Options{SegmentSize: 4096}
+		wal, err := CreateWithOptions(dir, 0, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 30; j++ {
+			if err = <-wal.Write(uint64(j), make([]byte, 300)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = <-wal.ReleaseBefore(test.index); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if err = wal.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		names, err := readAllWalNames(dir, opts.withDefaults().Logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(names) != test.files {
+			t.Errorf("#%d: files = %d, want %d", i, len(names), test.files)
+		}
+		if _, first := mustParseWalName(names[0]); first != test.first {
+			t.Errorf("#%d: first index = %d, want %d", i, first, test.first)
+		}
+
+		count := uint64(0)
+		wal, err = OpenWithOptions(dir, test.first, func(index uint64, data []byte) error {
+			if index != test.first+count {
+				t.Fatalf("#%d: index = %d, want %d", i, index, test.first+count)
+			}
+			count++
+			return nil
+		}, opts)
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if test.first+count != 30 {
+			t.Errorf("#%d: replay stop at %d, want 30", i, test.first+count)
+		}
+		if err = <-wal.Write(30, []byte{0x1}); err != nil {
+			t.Fatal(err)
+		}
+		wal.Close()
+		os.RemoveAll(dir)
+	}
+}
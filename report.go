@@ -0,0 +1,108 @@
+package wal
+
+import (
+	"fmt"
+	"time"
+)
+
+// RepairKind tells how a range of wal file is repaired by Open.
+type RepairKind int
+
+const (
+	// RepairTruncated means the range at the end of file is truncated.
+	RepairTruncated RepairKind = iota
+	// RepairSkipped means the range is skipped, but still in file.
+	RepairSkipped
+	// RepairDiscarded means the whole file is removed.
+	RepairDiscarded
+)
+
+func (kind RepairKind) String() string {
+	switch kind {
+	case RepairTruncated:
+		return "truncated"
+	case RepairSkipped:
+		return "skipped"
+	case RepairDiscarded:
+		return "discarded"
+	default:
+		return "unknown"
+	}
+}
+
+// Repair describes a range of wal file repaired or discarded by Open.
+type Repair struct {
+	Kind   RepairKind
+	File   string
+	Offset int64
+	// Length is the bytes read from Offset by Open, the corrupted
+	// frame is counted only if its length is known, the preallocated
+	// space after frames is never counted.
+	Length int64
+	// Err is the reason of repair.
+	Err error
+}
+
+// SegmentReport describes a wal file visited by Open.
+type SegmentReport struct {
+	File     string
+	Sequence uint64
+	// FirstIndex and LastIndex are the range of indexes of records
+	// in file, LastIndex is FirstIndex - 1 if there no any records.
+	FirstIndex uint64
+	LastIndex  uint64
+	// Records is the number of valid records in file.
+	Records int
+	// Bytes is the size of valid content in file, includes header.
+	Bytes int64
+}
+
+// RecoveryReport describes what Open did, so it could be logged
+// and alerted on.
+type RecoveryReport struct {
+	Mode     RecoveryMode
+	Segments []SegmentReport
+	// FirstIndex and LastIndex are the range of indexes of records
+	// replayed, only valid if Replayed great than zero.
+	FirstIndex uint64
+	LastIndex  uint64
+	// Replayed is the number of records passed to consumer.
+	Replayed int
+	// Skipped is the number of records read but index less than lsn,
+	// the ones skipped by sparse index are not counted.
+	Skipped int
+	// Bytes is the sum of Bytes of all visited files.
+	Bytes    int64
+	Duration time.Duration
+	Repairs  []Repair
+}
+
+func (report *RecoveryReport) String() string {
+	return fmt.Sprintf("recovery mode: %v, segments: %d, replayed: %d [%d, %d], "+
+		"skipped: %d, bytes: %d, repairs: %d, duration: %v",
+		report.Mode, len(report.Segments), report.Replayed, report.FirstIndex,
+		report.LastIndex, report.Skipped, report.Bytes, len(report.Repairs),
+		report.Duration)
+}
+
+func (report *RecoveryReport) consume(index uint64, lsn uint64) {
+	if index < lsn {
+		report.Skipped++
+		return
+	}
+	if report.Replayed == 0 {
+		report.FirstIndex = index
+	}
+	report.LastIndex = index
+	report.Replayed++
+}
+
+func (report *RecoveryReport) repair(kind RepairKind, filename string, offset, length int64, err error) {
+	report.Repairs = append(report.Repairs, Repair{
+		Kind:   kind,
+		File:   filename,
+		Offset: offset,
+		Length: length,
+		Err:    err,
+	})
+}
@@ -0,0 +1,157 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// frameSize is the size of frames written by createFileWithRecords,
+// the header of frame is 17 bytes.
+const frameSize = 17 + 1
+
+func TestRecoveryReport(t *testing.T) {
+	dir := createCorruptedWal(t)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	opts.RecoveryMode = SkipAnyCorruptedRecords
+	wal, report, err := OpenWithReport(dir, 5, emptyConsumer, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if report.Mode != SkipAnyCorruptedRecords {
+		t.Errorf("mode = %v, want %v", report.Mode, SkipAnyCorruptedRecords)
+	}
+	if len(report.Segments) != 3 {
+		t.Fatalf("segments = %d, want 3", len(report.Segments))
+	}
+	for i, segment := range report.Segments {
+		first := uint64(i * 10)
+		if segment.Sequence != uint64(i) || segment.FirstIndex != first ||
+			segment.LastIndex != first+9 {
+			t.Errorf("#%d: segment = %+v", i, segment)
+		}
+	}
+	if report.Segments[1].Records != 9 {
+		t.Errorf("records = %d, want 9", report.Segments[1].Records)
+	}
+	if report.Replayed != 24 || report.Skipped != 5 {
+		t.Errorf("replayed = %d, skipped = %d, want 24, 5", report.Replayed, report.Skipped)
+	}
+	if report.FirstIndex != 5 || report.LastIndex != 29 {
+		t.Errorf("range = [%d, %d], want [5, 29]", report.FirstIndex, report.LastIndex)
+	}
+	var bytes int64
+	for _, segment := range report.Segments {
+		bytes += segment.Bytes
+	}
+	if report.Bytes != bytes || bytes == 0 {
+		t.Errorf("bytes = %d, want %d", report.Bytes, bytes)
+	}
+	if len(report.Repairs) != 1 {
+		t.Fatalf("repairs = %d, want 1", len(report.Repairs))
+	}
+	repair := report.Repairs[0]
+	if repair.Kind != RepairSkipped || repair.File != filepath.Join(dir, walName(1, 10)) ||
+		repair.Length != frameSize || repair.Err == nil {
+		t.Errorf("repair = %+v", repair)
+	}
+}
+
+func TestRecoveryReport_PointInTime(t *testing.T) {
+	dir := createCorruptedWal(t)
+	defer os.RemoveAll(dir)
+
+	opts := DefaultOptions()
+	opts.RecoveryMode = PointInTimeRecovery
+	wal, report, err := OpenWithReport(dir, 0, emptyConsumer, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if len(report.Segments) != 2 {
+		t.Errorf("segments = %d, want 2", len(report.Segments))
+	}
+	if report.Replayed != 15 || report.LastIndex != 14 {
+		t.Errorf("replayed = %d, last = %d, want 15, 14", report.Replayed, report.LastIndex)
+	}
+
+	wants := []struct {
+		kind   RepairKind
+		file   string
+		length int64
+	}{
+		{RepairDiscarded, walName(2, 20), 64 + 10*frameSize},
+		{RepairTruncated, walName(1, 10), frameSize},
+	}
+	if len(report.Repairs) != len(wants) {
+		t.Fatalf("repairs = %d, want %d", len(report.Repairs), len(wants))
+	}
+	for i, want := range wants {
+		repair := report.Repairs[i]
+		if repair.Kind != want.kind || filepath.Base(repair.File) != want.file {
+			t.Errorf("#%d: repair = %+v, want %v of %s", i, repair, want.kind, want.file)
+		}
+		if repair.Length != want.length {
+			t.Errorf("#%d: length = %d, want %d", i, repair.Length, want.length)
+		}
+	}
+}
+
+func TestRecoveryReport_Failed(t *testing.T) {
+	dir := createCorruptedWal(t)
+	defer os.RemoveAll(dir)
+
+	_, report, err := OpenWithReport(dir, 0, emptyConsumer, DefaultOptions())
+	if err == nil {
+		t.Fatal("want open failed")
+	}
+	if report == nil || len(report.Segments) != 1 || report.Replayed != 15 {
+		t.Errorf("report = %v", report)
+	}
+}
+
+func TestRecoveryReport_UncommittedBatch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeBatch(t, wal, 0, 5, 100)
+	writeBatch(t, wal, 5, 10, 1000)
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dropLastCommit(t, dir)
+
+	wal, report, err := OpenWithReport(dir, 0, emptyConsumer, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	// the records of batch cross files.
+	if len(report.Repairs) < 2 {
+		t.Fatalf("repairs = %d, want great than 1", len(report.Repairs))
+	}
+	var length, want int64 = 0, 10 * (17 + 1000)
+	for i, repair := range report.Repairs {
+		if repair.Kind == RepairDiscarded {
+			want += 64
+		}
+		if repair.Length <= 0 {
+			t.Errorf("#%d: repair = %+v", i, repair)
+		}
+		length += repair.Length
+	}
+	if length != want {
+		t.Errorf("length = %d, want %d", length, want)
+	}
+}
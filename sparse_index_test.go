@@ -0,0 +1,169 @@
+package wal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/thinkermao/wal-go/record"
+)
+
+func TestOpen_SeekBySparseIndex(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 1024 * 1024}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 1000)
+	for i := 0; i < 2000; i++ {
+		if err = <-wal.Write(uint64(i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) < 2 {
+		t.Fatalf("files = %d, want rotated", len(names))
+	}
+	sealed := filepath.Join(dir, names[0])
+	if _, err = os.Stat(sealed + record.IndexFileSuffix); err != nil {
+		t.Fatalf("sealed file has no sidecar: %v", err)
+	}
+
+	tests := []struct {
+		removeSidecar bool
+		maxSkipped    int
+	}{
+		{false, 100},
+		// rebuilt by the first Open.
+		{true, 1000},
+		{false, 100},
+	}
+	for i, test := range tests {
+		if test.removeSidecar {
+			os.Remove(sealed + record.IndexFileSuffix)
+		}
+		wal, report, err := OpenWithReport(dir, 900, emptyConsumer, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wal.Close()
+
+		if report.FirstIndex != 900 || report.LastIndex != 1999 {
+			t.Errorf("#%d: replayed [%d, %d], want [900, 1999]",
+				i, report.FirstIndex, report.LastIndex)
+		}
+		if report.Skipped > test.maxSkipped || (test.removeSidecar && report.Skipped != 900) {
+			t.Errorf("#%d: skipped = %d, want <= %d", i, report.Skipped, test.maxSkipped)
+		}
+	}
+}
+
+func TestSparseIndex_Interval(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 256 * 1024}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 10)
+	for i := 0; i < 20000; i++ {
+		if err = <-wal.Write(uint64(i), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) < 2 {
+		t.Fatalf("files = %d, want rotated", len(names))
+	}
+	stat, err := os.Stat(filepath.Join(dir, names[0]) + record.IndexFileSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// header, crc32c and an entry per 64KB of records.
+	if count := (stat.Size() - 16) / 16; count < 4 || count > 5 {
+		t.Errorf("entries = %d, want an entry per 64KB", count)
+	}
+}
+
+// TestSparseIndex_Batch reads from the last record of batch, which has the
+// same index as the commit of batch.
+func TestSparseIndex_Batch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 128 * 1024}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(0, []byte{0x0}); err != nil {
+		t.Fatal(err)
+	}
+	// only the commit is far enough from the first record to be
+	// indexed if the records of batch are indexed.
+	batch := NewBatch()
+	batch.Add(1, make([]byte, 65470))
+	batch.Add(2, []byte{0x2})
+	batch.Add(3, []byte{0x3})
+	if err = <-wal.WriteBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(4, make([]byte, 64*1024)); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(5, []byte{0x5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(wal.recordFiles) != 2 {
+		t.Fatalf("files = %d, want 2", len(wal.recordFiles))
+	}
+
+	data, err := wal.Entry(3)
+	if err != nil || len(data) != 1 || data[0] != 0x3 {
+		t.Errorf("entry 3 = %v, %v, want [3]", data, err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var indexes []uint64
+	wal, err = OpenWithOptions(dir, 3, func(index uint64, data []byte) error {
+		indexes = append(indexes, index)
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.Close()
+	if len(indexes) != 3 || indexes[0] != 3 || indexes[2] != 5 {
+		t.Errorf("replayed %v, want [3 4 5]", indexes)
+	}
+
+	reader, err := NewReader(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if !reader.Next() || reader.Index() != 3 {
+		t.Errorf("first index = %d, err = %v, want 3", reader.Index(), reader.Err())
+	}
+}
@@ -0,0 +1,82 @@
+package wal
+
+import "time"
+
+const defaultSyncInterval = 10 * time.Millisecond
+
+// SyncMode decides when wal syncs written records to disk.
+type SyncMode int
+
+const (
+	// SyncManual never syncs automatically, records are persisted
+	// when caller calls Sync, or wal rotates to new file. The result
+	// of Write is resolved once record is written to buffer.
+	SyncManual SyncMode = iota
+	// SyncEveryWrite syncs after every write.
+	SyncEveryWrite
+	// SyncInterval syncs written records every SyncPolicy.Interval.
+	SyncInterval
+	// SyncThreshold syncs once the size of written records reaches
+	// SyncPolicy.Bytes, or the number of them reaches SyncPolicy.Records,
+	// or the records have waited SyncPolicy.MaxDelay, so a single caller
+	// waiting the result of Write isn't blocked forever.
+	SyncThreshold
+)
+
+func (mode SyncMode) String() string {
+	switch mode {
+	case SyncManual:
+		return "SyncManual"
+	case SyncEveryWrite:
+		return "SyncEveryWrite"
+	case SyncInterval:
+		return "SyncInterval"
+	case SyncThreshold:
+		return "SyncThreshold"
+	default:
+		return "UnknownSyncMode"
+	}
+}
+
+// SyncPolicy decides when wal syncs written records to disk. Except
+// SyncManual, the result of Write is resolved only after the record
+// has been synced.
+type SyncPolicy struct {
+	Mode SyncMode
+	// Interval used by SyncInterval, default is 10ms.
+	Interval time.Duration
+	// Bytes and Records used by SyncThreshold, zero means no limit,
+	// if both of them are zero, it same as SyncEveryWrite.
+	Bytes   int64
+	Records int
+	// MaxDelay used by SyncThreshold, it's the longest time a written
+	// record waits for sync, default is 10ms.
+	MaxDelay time.Duration
+}
+
+func (policy *SyncPolicy) withDefaults() SyncPolicy {
+	result := *policy
+	if result.Mode == SyncInterval && result.Interval <= 0 {
+		result.Interval = defaultSyncInterval
+	}
+	if result.Mode == SyncThreshold && result.MaxDelay <= 0 {
+		result.MaxDelay = defaultSyncInterval
+	}
+	return result
+}
+
+// shouldSync test whether unsynced records reach the threshold of policy.
+func (policy *SyncPolicy) shouldSync(bytes int64, records int) bool {
+	switch policy.Mode {
+	case SyncEveryWrite:
+		return true
+	case SyncThreshold:
+		if policy.Bytes <= 0 && policy.Records <= 0 {
+			return true
+		}
+		return (policy.Bytes > 0 && bytes >= policy.Bytes) ||
+			(policy.Records > 0 && records >= policy.Records)
+	default:
+		return false
+	}
+}
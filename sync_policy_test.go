@@ -0,0 +1,184 @@
+package wal
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSyncPolicy_ShouldSync(t *testing.T) {
+	tests := []struct {
+		policy  SyncPolicy
+		bytes   int64
+		records int
+		want    bool
+	}{
+		{SyncPolicy{Mode: SyncManual}, 100, 100, false},
+		{SyncPolicy{Mode: SyncEveryWrite}, 1, 1, true},
+		{SyncPolicy{Mode: SyncInterval}, 100, 100, false},
+		{SyncPolicy{Mode: SyncThreshold}, 1, 1, true},
+		{SyncPolicy{Mode: SyncThreshold, Bytes: 100}, 99, 100, false},
+		{SyncPolicy{Mode: SyncThreshold, Bytes: 100}, 100, 1, true},
+		{SyncPolicy{Mode: SyncThreshold, Records: 10}, 1000, 9, false},
+		{SyncPolicy{Mode: SyncThreshold, Records: 10}, 1, 10, true},
+		{SyncPolicy{Mode: SyncThreshold, Bytes: 100, Records: 10}, 1, 10, true},
+	}
+	for i, test := range tests {
+		if g := test.policy.shouldSync(test.bytes, test.records); g != test.want {
+			t.Errorf("#%d: should sync = %v, want %v", i, g, test.want)
+		}
+	}
+}
+
+func isResolved(ch <-chan error) bool {
+	select {
+	case err := <-ch:
+		if err != nil {
+			panic(err)
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func createWithSyncPolicy(t *testing.T, policy SyncPolicy) (string, *Wal) {
+	dir := createTmpDir(t)
+	wal, err := CreateWithOptions(dir, 1, &Options{SyncPolicy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, wal
+}
+
+func TestSyncPolicy_Threshold(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncThreshold, Records: 3, MaxDelay: time.Hour})
+	defer os.RemoveAll(dir)
+	defer wal.Close()
+
+	ch1 := wal.Write(1, []byte{0x1})
+	ch2 := wal.Write(2, []byte{0x2})
+	// let the writes be handled by service.
+	time.Sleep(20 * time.Millisecond)
+	if isResolved(ch1) || isResolved(ch2) {
+		t.Fatalf("write resolved before sync")
+	}
+	if wal.LastSyncedIndex() != 0 {
+		t.Fatalf("synced = %d, want 0", wal.LastSyncedIndex())
+	}
+	ch3 := wal.Write(3, []byte{0x3})
+	if err := <-ch3; err != nil {
+		t.Fatal(err)
+	}
+	if !isResolved(ch1) || !isResolved(ch2) {
+		t.Fatalf("writes must be resolved by sync")
+	}
+}
+
+func TestSyncPolicy_ThresholdMaxDelay(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncThreshold, Records: 100, MaxDelay: 20 * time.Millisecond})
+	defer os.RemoveAll(dir)
+	defer wal.Close()
+
+	// the threshold is never reached by a single caller.
+	for i := 1; i < 5; i++ {
+		select {
+		case err := <-wal.Write(uint64(i), []byte{0x1}):
+			if err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("write %d isn't synced by max delay", i)
+		}
+	}
+	if wal.LastSyncedIndex() != 4 {
+		t.Errorf("synced = %d, want 4", wal.LastSyncedIndex())
+	}
+}
+
+func TestSyncPolicy_Durable(t *testing.T) {
+	policies := []SyncPolicy{
+		{Mode: SyncEveryWrite},
+		{Mode: SyncInterval, Interval: 5 * time.Millisecond},
+		{Mode: SyncThreshold, Records: 3, MaxDelay: 5 * time.Millisecond},
+		{Mode: SyncThreshold, Bytes: 10, MaxDelay: 5 * time.Millisecond},
+	}
+	for _, policy := range policies {
+		dir, wal := createWithSyncPolicy(t, policy)
+
+		// written by a single caller, and pipelined.
+		for i := 1; i <= 5; i++ {
+			if err := <-wal.Write(uint64(i), []byte{0x1}); err != nil {
+				t.Fatal(err)
+			}
+			if synced := wal.LastSyncedIndex(); synced < uint64(i) {
+				t.Errorf("%v: write %d resolved, but synced = %d", policy.Mode, i, synced)
+			}
+		}
+		results := make([]<-chan error, 0)
+		for i := 6; i <= 50; i++ {
+			results = append(results, wal.Write(uint64(i), []byte{0x1}))
+		}
+		for i, ch := range results {
+			if err := <-ch; err != nil {
+				t.Fatal(err)
+			}
+			if synced := wal.LastSyncedIndex(); synced < uint64(i+6) {
+				t.Errorf("%v: write %d resolved, but synced = %d", policy.Mode, i+6, synced)
+			}
+		}
+		wal.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSyncPolicy_Interval(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncInterval, Interval: 20 * time.Millisecond})
+	defer os.RemoveAll(dir)
+	defer wal.Close()
+
+	start := time.Now()
+	for i := 1; i < 10; i++ {
+		if err := <-wal.Write(uint64(i), []byte{0x1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("writes should be resolved by interval sync")
+	}
+}
+
+func TestSyncPolicy_ManualSync(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncInterval, Interval: time.Hour})
+	defer os.RemoveAll(dir)
+
+	ch := wal.Write(1, []byte{0x1})
+	if err := <-wal.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if !isResolved(ch) {
+		t.Fatalf("write must be resolved by sync")
+	}
+
+	ch = wal.Write(2, []byte{0x2})
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !isResolved(ch) {
+		t.Fatalf("write must be resolved by close")
+	}
+}
+
+func TestSyncPolicy_Manual(t *testing.T) {
+	dir, wal := createWithSyncPolicy(t, SyncPolicy{Mode: SyncManual})
+	defer os.RemoveAll(dir)
+	defer wal.Close()
+
+	ch := wal.Write(1, []byte{0x1})
+	if err := <-wal.Write(2, []byte{0x2}); err != nil {
+		t.Fatal(err)
+	}
+	if !isResolved(ch) {
+		t.Fatalf("write must be resolved without sync")
+	}
+}
@@ -0,0 +1,264 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestTail(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err = <-wal.Write(uint64(i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reader, err := wal.Tail(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	total := 300
+	go func() {
+		// records cross files, and some of them are batches.
+		for i := 10; i < total; {
+			if i%7 == 0 && i+3 <= total {
+				batch := NewBatch()
+				for j := 0; j < 3; j++ {
+					batch.Add(uint64(i+j), make([]byte, 100))
+				}
+				if err := <-wal.WriteBatch(batch); err != nil {
+					t.Error(err)
+					return
+				}
+				i += 3
+				continue
+			}
+			if err := <-wal.Write(uint64(i), make([]byte, 100)); err != nil {
+				t.Error(err)
+				return
+			}
+			i++
+		}
+		wal.Close()
+	}()
+
+	next := uint64(5)
+	for reader.Next() {
+		if reader.Index() != next {
+			t.Fatalf("index = %d, want %d", reader.Index(), next)
+		}
+		next++
+	}
+	if err = reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if next != uint64(total) {
+		t.Errorf("stop at %d, want %d", next, total)
+	}
+}
+
+func TestTail_Close(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	reader, err := wal.Tail(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- reader.Next()
+	}()
+	select {
+	case <-done:
+		t.Fatal("Next returns without records")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if err = reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if <-done {
+		t.Errorf("Next returns true after Close")
+	}
+	if !errors.Is(reader.Err(), ErrReaderClosed) {
+		t.Errorf("err = %v, want %v", reader.Err(), ErrReaderClosed)
+	}
+}
+
+func TestTail_Failed(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := wal.Tail(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if !reader.Next() || reader.Index() != 1 {
+		t.Fatalf("want record 1, err = %v", reader.Err())
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		// the records couldn't be flushed any more.
+		wal.back().file.Close()
+		<-wal.Write(2, []byte{0x2})
+	}()
+
+	done := make(chan bool)
+	go func() {
+		done <- reader.Next()
+	}()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatalf("want no more records, get %d", reader.Index())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reader isn't waked up by failure")
+	}
+	if err = reader.Err(); !errors.Is(err, ErrWalFailed) {
+		t.Errorf("err = %v, want %v", err, ErrWalFailed)
+	}
+}
+
+// expectTruncated expects Next of reader returns false with ErrTruncated.
+func expectTruncated(t *testing.T, reader *Reader) {
+	done := make(chan bool)
+	go func() {
+		done <- reader.Next()
+	}()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatalf("want no more records, get %d", reader.Index())
+		}
+	case <-time.After(time.Second):
+		reader.Close()
+		<-done
+		t.Fatal("reader isn't stopped by truncation")
+	}
+	if err := reader.Err(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("err = %v, want %v", err, ErrTruncated)
+	}
+}
+
+func TestTail_Truncated(t *testing.T) {
+	tests := []struct {
+		segment int64
+		size    int // size of records written
+		resize  int // size of records written after truncation
+	}{
+		{0, 1, 100},
+		{0, 100, 100},
+		{4096, 500, 500},
+		{4096, 500, 10},
+	}
+	for i, test := range tests {
+		dir := createTmpDir(t)
+		wal, err := CreateWithOptions(dir, 0, &Options{SegmentSize: test.segment})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 10; j++ {
+			if err = <-wal.Write(uint64(j), make([]byte, test.size)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		reader, err := wal.Tail(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 10; j++ {
+			if !reader.Next() || reader.Index() != uint64(j) {
+				t.Fatalf("#%d: want record %d, err = %v", i, j, reader.Err())
+			}
+		}
+
+		if err = <-wal.TruncateAfter(4); err != nil {
+			t.Fatal(err)
+		}
+		data := make([]byte, test.resize)
+		data[0] = 0xff
+		for j := 5; j < 20; j++ {
+			if err = <-wal.Write(uint64(j), data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		expectTruncated(t, reader)
+		reader.Close()
+		wal.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestTail_TruncatedUnread(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	write := func(from, to int, b byte) {
+		for i := from; i < to; i++ {
+			if err := <-wal.Write(uint64(i), []byte{b}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	write(0, 5, 0x1)
+	reader, err := wal.Tail(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for i := 0; i < 5; i++ {
+		if !reader.Next() {
+			t.Fatal(reader.Err())
+		}
+	}
+
+	// the records not read yet are replaced.
+	write(5, 10, 0x1)
+	if err = <-wal.TruncateAfter(7); err != nil {
+		t.Fatal(err)
+	}
+	write(8, 12, 0x2)
+	for i := 5; i < 12; i++ {
+		if !reader.Next() || reader.Index() != uint64(i) {
+			t.Fatalf("want record %d, err = %v", i, reader.Err())
+		}
+		if i >= 8 && reader.Data()[0] != 0x2 {
+			t.Errorf("record %d isn't the one written after truncation", i)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/thinkermao/wal-go/file"
+	"github.com/thinkermao/wal-go/record"
+)
+
+// ErrIndexOutOfRange means the records at index aren't in wal, such
+// as released or not written.
+var ErrIndexOutOfRange = errors.New("index out of range of wal")
+
+// errStopScan used to stop scanning file at the point of truncation.
+var errStopScan = errors.New("stop scan")
+
+// truncateAfter discards all records whose index great than index.
+// The files after the one contains index are removed at first, so
+// the wal is always valid even if crashed in the middle.
+func (wal *Wal) truncateAfter(index uint64) error {
+	files := wal.recordFiles
+	if index+1 < files[0].index {
+		return fmt.Errorf("%w: truncate after %d, first index %d",
+			ErrIndexOutOfRange, index, files[0].index)
+	}
+	if index >= wal.back().lastIndex {
+		return nil
+	}
+	wal.discardTails(index + 1)
+	wal.allocator.wait()
+
+	k := len(files) - 1
+	for k > 0 && files[k].index > index+1 {
+		k--
+	}
+
+	// the kept records of batch lost their commit, count
+	// them to write a new commit.
+	offset, pending, all, err := scanTruncatePoint(files[k].file, index)
+	if err != nil {
+		return err
+	}
+	for j := k - 1; all && j >= 0; j-- {
+		var n int
+		if n, all, err = countTrailingBatch(files[j].file); err != nil {
+			return err
+		}
+		pending += n
+	}
+
+	for j := len(files) - 1; j > k; j-- {
+		if err = files[j].file.Close(); err != nil {
+			return err
+		}
+		if err = record.RemoveFile(files[j].filename); err != nil {
+			return err
+		}
+		wal.mu.Lock()
+		wal.recordFiles = files[:j]
+		wal.mu.Unlock()
+	}
+	if err = file.SyncDir(wal.walDir); err != nil {
+		return err
+	}
+
+	rf := files[k]
+	if err = rf.file.TruncateAt(offset, index); err != nil {
+		return err
+	}
+	if pending > 0 {
+		if err = rf.file.WriteCommit(index, pending); err != nil {
+			return err
+		}
+		if err = rf.file.Sync(); err != nil {
+			return err
+		}
+	}
+	rf.lastIndex = index
+	atomic.StoreUint64(&wal.nextIndex, index+1)
+	// the rest of records are synced by truncation.
+	wal.setSynced(index)
+	return nil
+}
+
+// scanTruncatePoint returns the offset of the first record whose index
+// great than index, the number of records of batch just before it, and
+// whether all the records before it are records of batch.
+func scanTruncatePoint(file *record.File, index uint64) (offset int64, pending int, all bool, err error) {
+	if err = file.Rewind(); err != nil {
+		return
+	}
+
+	offset, all = -1, true
+	err = file.Replay(func(r *record.Record) error {
+		if r.Index > index {
+			offset = r.Offset
+			return errStopScan
+		}
+		if r.Type == record.RecordBatch {
+			pending++
+		} else {
+			pending, all = 0, false
+		}
+		return nil
+	})
+	if err == errStopScan {
+		err = nil
+	}
+	if offset < 0 {
+		offset = file.Offset()
+	}
+	return
+}
+
+// countTrailingBatch returns the number of records of batch at the end
+// of file, and whether all records of file are records of batch.
+func countTrailingBatch(file *record.File) (pending int, all bool, err error) {
+	if err = file.Rewind(); err != nil {
+		return
+	}
+
+	all = true
+	err = file.Replay(func(r *record.Record) error {
+		if r.Type == record.RecordBatch {
+			pending++
+		} else {
+			pending, all = 0, false
+		}
+		return nil
+	})
+	return
+}
+
+// discardTails tell tailing readers the records from index are
+// discarded, it must be called before discarding, so the records
+// read in the middle are found.
+func (wal *Wal) discardTails(index uint64) {
+	wal.notifyMu.Lock()
+	defer wal.notifyMu.Unlock()
+	for t := range wal.tails {
+		t.discard(index)
+	}
+}
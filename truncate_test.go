@@ -0,0 +1,121 @@
+package wal
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWrite_NotContinuous(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.Write(2, []byte{0x1}); !errors.Is(err, ErrIndexNotContinuous) {
+		t.Errorf("err = %v, want %v", err, ErrIndexNotContinuous)
+	}
+	if err = <-wal.Write(1, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-wal.Write(1, []byte{0x1}); !errors.Is(err, ErrIndexNotContinuous) {
+		t.Errorf("err = %v, want %v", err, ErrIndexNotContinuous)
+	}
+}
+
+// TestTruncateAfter writes records in [0, 30) to three files, the
+// first two of them have 13 records.
+func TestTruncateAfter(t *testing.T) {
+	tests := []struct {
+		index uint64
+		want  uint64
+		werr  error
+	}{
+		{30, 30, nil}, // no records truncated
+		{27, 28, nil}, // in the last file
+		{20, 21, nil}, // across files
+		{13, 14, nil}, // at the first record of file
+		{12, 13, nil}, // at the boundary of files
+		{0, 1, nil},   // all but the first record
+	}
+	for i, test := range tests {
+		dir := createTmpDir(t)
+		opts := &Options{SegmentSize: 4096}
+		wal, err := CreateWithOptions(dir, 0, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 30; j++ {
+			if err = <-wal.Write(uint64(j), make([]byte, 300)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = <-wal.TruncateAfter(test.index); !errors.Is(err, test.werr) {
+			t.Fatalf("#%d: err = %v, want %v", i, err, test.werr)
+		}
+		if err = <-wal.Write(test.want, []byte{0x1}); err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		if err = wal.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		wal, count := openAndCount(t, dir, opts)
+		if count != test.want+1 {
+			t.Errorf("#%d: count = %d, want %d", i, count, test.want+1)
+		}
+		wal.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestTruncateAfter_OutOfRange(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	wal, err := Create(dir, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	if err = <-wal.TruncateAfter(3); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Errorf("err = %v, want %v", err, ErrIndexOutOfRange)
+	}
+	if err = <-wal.TruncateAfter(4); err != nil {
+		t.Errorf("err = %v, want nil", err)
+	}
+}
+
+func TestTruncateAfter_Batch(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 4096}
+	wal, err := CreateWithOptions(dir, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeBatch(t, wal, 0, 30, 300)
+	if err = <-wal.TruncateAfter(20); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	wal, count := openAndCount(t, dir, opts)
+	if count != 21 {
+		t.Errorf("count = %d, want 21", count)
+	}
+	if err = <-wal.Write(21, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
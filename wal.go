@@ -1,14 +1,31 @@
 package wal
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/thinkermao/wal-go/file"
 	"github.com/thinkermao/wal-go/record"
 )
 
-const defaultSequence = 0
+const (
+	defaultSequence = 0
+	maxBatchSize    = 1024 // max commands handled as a batch
+)
+
+var (
+	// ErrIndexNotContinuous means the index of written record isn't
+	// the index of last record plus one.
+	ErrIndexNotContinuous = errors.New("index of record isn't continuous")
+	// ErrWalClosed is returned by the waiters of wal after it closed.
+	ErrWalClosed = errors.New("wal is closed")
+)
 
 type recordFile struct {
 	filename  string
@@ -22,80 +39,142 @@ type recordFile struct {
 // It provides the log persistence, recovery capabilities.
 // wal is thread-safe and supports concurrent calls.
 type Wal struct {
+	// keep 64-bit aligned for atomic operations.
+	nextIndex  uint64 // index of the next record to write
+	syncedNext uint64 // index of the first record not synced
+
 	walDir      string
+	opts        *Options
+	mu          sync.RWMutex // guards recordFiles, only modified by service
 	recordFiles []*recordFile
 	queue       chan<- command
+	done        chan struct{}
+	allocator   *allocator
+
+	tailers   int32      // number of tailing readers, atomic
+	notifyMu  sync.Mutex // guards notifyCh, durableCh, failure and tails
+	tails     map[*tailer]struct{}
+	notifyCh  chan struct{}
+	durableCh chan struct{}
+	failure   error // sticky, set by service on the first I/O error
+
+	// owned by service goroutine.
+	pending         []command // commands wait for sync
+	written         bool      // records written by batch
+	unsyncedBytes   int64
+	unsyncedRecords int
+	syncs           int // number of syncs of files
 }
 
 // Create returns Wal instance with initialize index.
 func Create(walDir string, initialize uint64) (*Wal, error) {
+	return CreateWithOptions(walDir, initialize, DefaultOptions())
+}
+
+// CreateWithOptions same as Create, but with opts.
+func CreateWithOptions(walDir string, initialize uint64, opts *Options) (*Wal, error) {
+	opts = opts.withDefaults()
 	if !file.IsExists(walDir) {
-		if err := os.MkdirAll(walDir, 0766); err != nil {
+		if err := os.MkdirAll(walDir, opts.DirPerm); err != nil {
+			return nil, err
+		}
+		if err := file.SyncDir(filepath.Dir(filepath.Clean(walDir))); err != nil {
 			return nil, err
 		}
 	}
 
+	// the removal is durable once the first file is created.
 	if err := file.ClearAllEndsWith(walDir, ".wal"); err != nil {
 		return nil, err
 	}
+	if err := file.ClearAllEndsWith(walDir, record.IndexFileSuffix); err != nil {
+		return nil, err
+	}
 
 	recordFiles := make([]*recordFile, 0)
-	rf, err := createFile(walDir, defaultSequence, initialize)
+	rf, err := createFile(walDir, defaultSequence, initialize, opts)
 	if err != nil {
 		return nil, err
 	}
 	recordFiles = append(recordFiles, rf)
 
-	queue := make(chan command)
-
-	wal := &Wal{
-		walDir:      walDir,
-		recordFiles: recordFiles,
-		queue:       queue,
-	}
-	go wal.service(queue)
-	return wal, nil
+	return newWal(walDir, opts, recordFiles), nil
 }
 
 // Open find the first wal file has index large than lsn, and
-// read, poll it to consumer.
+// read, poll it to consumer. If the last wal file ends with torn
+// or corrupted records, such as the process crashed in the middle
+// of writing, they are truncated and the wal is reopened for
+// appending after the last valid record. Corruption in other
+// files is a hard error.
 func Open(walDir string, lsn uint64, consumer record.Consumer) (*Wal, error) {
+	return OpenWithOptions(walDir, lsn, consumer, DefaultOptions())
+}
+
+// OpenWithOptions same as Open, but with opts, corrupted records
+// are processed according to opts.RecoveryMode.
+func OpenWithOptions(walDir string, lsn uint64, consumer record.Consumer, opts *Options) (*Wal, error) {
+	wal, _, err := OpenWithReport(walDir, lsn, consumer, opts)
+	return wal, err
+}
+
+// OpenWithReport same as OpenWithOptions, but also returns a report
+// describes what it did, the report is returned even if it fails.
+func OpenWithReport(walDir string, lsn uint64, consumer record.Consumer, opts *Options) (*Wal, *RecoveryReport, error) {
+	opts = opts.withDefaults()
+	start := time.Now()
+	report := &RecoveryReport{Mode: opts.RecoveryMode}
+	defer func() {
+		report.Duration = time.Since(start)
+	}()
+
 	// remove all stale tmp files
 	if err := file.ClearAllEndsWith(walDir, ".tmp"); err != nil {
-		return nil, err
+		return nil, report, err
 	}
-	names, err := readAllWalNames(walDir)
+	names, err := readAllWalNames(walDir, opts.Logger)
 	if err != nil {
-		return nil, err
+		return nil, report, err
 	}
 
 	index, ok := searchIndex(names, lsn)
 	if !ok || !isValidSequences(names[index:]) {
-		return nil, errFileNotFound
+		return nil, report, fmt.Errorf("open %s at index %d: %w", walDir, lsn, ErrFileNotFound)
 	}
 
-	recordFiles := make([]*recordFile, 0)
-	for i := index; i < len(names); i++ {
-		path := filepath.Join(walDir, names[i])
-		f, err := record.RestoreFile(path, lsn, consumer)
-		if err != nil {
-			closeAll(recordFiles)
-			return nil, err
-		}
-		seq, idx := mustParseWalName(names[i])
-		recordFile := makeRecordFile(path, seq, idx, f)
-		recordFiles = append(recordFiles, recordFile)
+	recordFiles, err := recoverFiles(walDir, names[index:], lsn, consumer, opts, report)
+	if err != nil {
+		return nil, report, err
+	}
+	// the files repaired or removed by recovery.
+	if err = file.SyncDir(walDir); err != nil {
+		closeAll(recordFiles)
+		return nil, report, err
 	}
 
-	queue := make(chan command)
+	return newWal(walDir, opts, recordFiles), report, nil
+}
+
+// newWal returns Wal appends to the last one of recordFiles, the
+// records in them must be synced.
+func newWal(walDir string, opts *Options, recordFiles []*recordFile) *Wal {
+	queue := make(chan command, maxBatchSize)
 
 	wal := &Wal{
 		walDir:      walDir,
+		opts:        opts,
 		recordFiles: recordFiles,
 		queue:       queue,
+		done:        make(chan struct{}),
+		notifyCh:    make(chan struct{}),
+		durableCh:   make(chan struct{}),
+		tails:       make(map[*tailer]struct{}),
+		allocator:   newAllocator(walDir, opts),
 	}
+	next := wal.back().lastIndex + 1
+	wal.nextIndex, wal.syncedNext = next, next
 	go wal.service(queue)
-	return wal, nil
+	return wal
 }
 
 // Sync used by customer to write buffered data to file.
@@ -105,25 +184,135 @@ func (wal *Wal) Sync() <-chan error {
 	return ch
 }
 
-// Write store data to buffer.
+// Write store data to buffer. The result is resolved once the
+// durability guarantee of Options.SyncPolicy is met. index must
+// be the index of last record plus one.
 func (wal *Wal) Write(index uint64, data []byte) <-chan error {
 	cmd, ch := genAppend(index, data)
 	wal.queue <- cmd
 	return ch
 }
 
+// WriteCtx same as Write, but waits for the result, and returns
+// ctx.Err() if ctx is done before the result resolved. The record
+// is dropped if ctx is done before it's written, otherwise it may
+// still be persisted.
+func (wal *Wal) WriteCtx(ctx context.Context, index uint64, data []byte) error {
+	cmd, ch := genAppend(index, data)
+	return wal.execute(ctx, cmd, ch)
+}
+
+// SyncCtx same as Sync, but waits for the result like WriteCtx.
+func (wal *Wal) SyncCtx(ctx context.Context) error {
+	cmd, ch := genSync()
+	return wal.execute(ctx, cmd, ch)
+}
+
+// execute send cmd to service and wait for the result, until ctx done.
+func (wal *Wal) execute(ctx context.Context, cmd command, result <-chan error) error {
+	cmd.ctx = ctx
+	select {
+	case wal.queue <- cmd:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WriteBatch store records of b to buffer as a unit, on recovery
+// they are replayed only if all of them are written. The result is
+// resolved like Write, b could be reused after it's resolved.
+func (wal *Wal) WriteBatch(b *Batch) <-chan error {
+	if len(b.entries) == 0 {
+		result := make(chan error, 1)
+		result <- ErrEmptyBatch
+		close(result)
+		return result
+	}
+	cmd, ch := genBatch(b.entries)
+	wal.queue <- cmd
+	return ch
+}
+
+// TruncateAfter discards all records whose index great than index,
+// even if they are in different files. The result is resolved once
+// they are durably removed, so Open never replays them again. The
+// records written before it are synced first.
+func (wal *Wal) TruncateAfter(index uint64) <-chan error {
+	cmd, ch := genTruncate(index)
+	wal.queue <- cmd
+	return ch
+}
+
+// ReleaseBefore removes the files whose records are all below index,
+// such as the ones covered by a snapshot. The last file is never
+// removed. The result is resolved once the removal is durable.
+func (wal *Wal) ReleaseBefore(index uint64) <-chan error {
+	cmd, ch := genRelease(index)
+	wal.queue <- cmd
+	return ch
+}
+
+// LastIndex returns index of the last written record, or index
+// of the first record minus one if there no any records.
+func (wal *Wal) LastIndex() uint64 {
+	return atomic.LoadUint64(&wal.nextIndex) - 1
+}
+
+// LastSyncedIndex returns index of the last record on stable
+// storage, the records after it may be lost on power failure.
+// Readers and replication could use it to read durable records
+// only, see Entries and TailDurable.
+func (wal *Wal) LastSyncedIndex() uint64 {
+	return atomic.LoadUint64(&wal.syncedNext) - 1
+}
+
+// WaitDurable blocks until the record at index is synced, or ctx
+// done or wal closed or failed. It doesn't sync by itself, the
+// records are synced by Sync or Options.SyncPolicy.
+func (wal *Wal) WaitDurable(ctx context.Context, index uint64) error {
+	for {
+		// the notification must be taken before checking.
+		changed := wal.durableChanged()
+		if index < atomic.LoadUint64(&wal.syncedNext) {
+			return nil
+		}
+		if err := wal.Err(); err != nil {
+			return err
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wal.done:
+			if index < atomic.LoadUint64(&wal.syncedNext) {
+				return nil
+			}
+			return ErrWalClosed
+		}
+	}
+}
+
 // Close close working queue, so no any writer could
 // append, committed work will be execute. caller must
-// ensure no data race at here.
+// ensure no data race at here. The files are closed even
+// if wal failed, so it could be reopened by Open.
 func (wal *Wal) Close() error {
 	cmd, errChan := genSync()
 	wal.queue <- cmd
 	close(wal.queue)
 
 	err := <-errChan
-	if err != nil {
-		return err
+	<-wal.done
+	wal.allocator.close()
+	if cerr := closeAll(wal.recordFiles); err == nil {
+		err = cerr
 	}
-
-	return closeAll(wal.recordFiles)
+	return err
 }
@@ -3,6 +3,7 @@ package wal
 import (
 	"io/ioutil"
 	"os"
+	"sync"
 	"testing"
 )
 
@@ -37,7 +38,7 @@ func benchmarkWriteByte(b *testing.B, size int, batch int) {
 	n := 0
 	b.SetBytes(int64(len(data)))
 	for i := 0; i < b.N; i++ {
-		ch := w.Write(0, data)
+		ch := w.Write(uint64(i), data)
 		err = <-ch
 		if err != nil {
 			b.Fatal(err)
@@ -59,3 +60,64 @@ func benchmarkWriteByte(b *testing.B, size int, batch int) {
 	}
 	w.Close()
 }
+
+func BenchmarkConcurrentWriteSync1(b *testing.B)   { benchmarkConcurrentWriteSync(b, 100, 1) }
+func BenchmarkConcurrentWriteSync4(b *testing.B)   { benchmarkConcurrentWriteSync(b, 100, 4) }
+func BenchmarkConcurrentWriteSync16(b *testing.B)  { benchmarkConcurrentWriteSync(b, 100, 16) }
+func BenchmarkConcurrentWriteSync64(b *testing.B)  { benchmarkConcurrentWriteSync(b, 100, 64) }
+func BenchmarkConcurrentWriteSync256(b *testing.B) { benchmarkConcurrentWriteSync(b, 100, 256) }
+
+// benchmarkConcurrentWriteSync runs writers goroutines, each of them
+// calls Write and then Sync, concurrent syncs are coalesced by wal.
+func benchmarkConcurrentWriteSync(b *testing.B, size int, writers int) {
+	p, err := ioutil.TempDir(os.TempDir(), "waltest")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(p)
+
+	w, err := Create(p, 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	data := make([]byte, size)
+	for i := 0; i < size; i++ {
+		data[i] = byte(i)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var index uint64
+	ops := make(chan struct{}, b.N)
+	for i := 0; i < b.N; i++ {
+		ops <- struct{}{}
+	}
+	close(ops)
+
+	b.ResetTimer()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range ops {
+				// index must be continuous in queue.
+				mu.Lock()
+				index++
+				ch := w.Write(index, data)
+				mu.Unlock()
+				if err := <-ch; err != nil {
+					b.Error(err)
+					return
+				}
+				if err := <-w.Sync(); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+	w.Close()
+}
@@ -1,8 +1,13 @@
 package wal
 
 import (
+	"errors"
+	"fmt"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
+	"github.com/thinkermao/wal-go/file"
 	"github.com/thinkermao/wal-go/record"
 )
 
@@ -17,67 +22,398 @@ func makeRecordFile(filename string, seq, idx uint64, file *record.File) *record
 	return nrf
 }
 
-func createFile(dir string, seq, idx uint64) (*recordFile, error) {
+// createFile create record file in dir, the file is durable in dir
+// once it returns.
+func createFile(dir string, seq, idx uint64, opts *Options) (*recordFile, error) {
+	return createFileFrom("", dir, seq, idx, opts)
+}
+
+// createFileFrom same as createFile, but reuses the file preallocated
+// at tmpname if it isn't empty.
+func createFileFrom(tmpname, dir string, seq, idx uint64, opts *Options) (*recordFile, error) {
+	var rf *record.File
+	var err error
 	filename := filepath.Join(dir, walName(seq, idx))
-	file, err := record.CreateFile(filename)
+	if tmpname == "" {
+		rf, err = record.CreateFileWithOptions(filename, seq, idx, opts.recordOptions())
+	} else {
+		rf, err = record.CreateFileFrom(tmpname, filename, seq, idx, opts.recordOptions())
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err = file.SyncDir(dir); err != nil {
+		rf.Close()
+		return nil, err
+	}
 
-	nrf := makeRecordFile(filename, seq, idx, file)
+	nrf := makeRecordFile(filename, seq, idx, rf)
+	nrf.lastIndex = rf.LastIndex()
 	return nrf, nil
 }
 
+// closeAll close all files, and returns the first error.
 func closeAll(files []*recordFile) error {
+	var err error
 	for _, rf := range files {
-		if err := rf.file.Close(); err != nil {
-			return err
+		if cerr := rf.file.Close(); err == nil {
+			err = cerr
 		}
 	}
-	return nil
+	return err
 }
 
 func (wal *Wal) service(queue <-chan command) {
-	for cmd := range queue {
-		switch cmd.cmdType {
-		case cmdAppend:
-			if err := wal.back().file.Write(cmd.index, cmd.data); err != nil {
-				cmd.onFailure(err)
+	defer close(wal.done)
+
+	var tick <-chan time.Time
+	policy := &wal.opts.SyncPolicy
+	switch policy.Mode {
+	case SyncInterval:
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	case SyncThreshold:
+		// the records below threshold are synced by ticker.
+		ticker := time.NewTicker(policy.MaxDelay)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case cmd, ok := <-queue:
+			if !ok || wal.handleBatch(cmd, queue) {
+				if len(wal.pending) > 0 {
+					wal.ackPending(wal.sync())
+				}
+				return
 			}
-			if err := wal.rotateIfNeed(); err != nil {
-				cmd.onFailure(err)
+		case <-tick:
+			if len(wal.pending) > 0 {
+				wal.ackPending(wal.sync())
 			}
-			cmd.onSuccess()
+		}
+	}
+}
 
-		case cmdSync:
-			if err := wal.back().file.Sync(); err != nil {
-				cmd.onFailure(err)
-			} else {
-				cmd.onSuccess()
+// handleBatch handle cmd and the commands already in queue as a batch,
+// so the records written by concurrent callers are synced once, and
+// all of them are acknowledged together. It returns true if queue
+// is closed.
+func (wal *Wal) handleBatch(cmd command, queue <-chan command) (closed bool) {
+	needSync := wal.handle(&cmd)
+batch:
+	for i := 1; i < maxBatchSize; i++ {
+		select {
+		case next, ok := <-queue:
+			if !ok {
+				closed = true
+				break batch
 			}
+			needSync = wal.handle(&next) || needSync
+		default:
+			break batch
 		}
 	}
+	wal.commit(needSync)
+	wal.publish()
+	return closed
+}
+
+// handle execute cmd, and returns true if cmd requires sync.
+func (wal *Wal) handle(cmd *command) bool {
+	// the cancelled commands are dropped.
+	if err := cmd.cancelled(); err != nil {
+		cmd.onFailure(err)
+		return false
+	}
+	// nothing is executed after failed.
+	if wal.failure != nil {
+		cmd.onFailure(wal.failure)
+		return false
+	}
+
+	switch cmd.cmdType {
+	case cmdAppend:
+		if err := checkRecord(wal.back().lastIndex+1, cmd.index, cmd.data); err != nil {
+			cmd.onFailure(err)
+			return false
+		}
+		if err := wal.back().file.Write(cmd.index, cmd.data); err != nil {
+			err = wal.fail(err)
+			cmd.onFailure(err)
+			wal.ackPending(err)
+			return false
+		}
+		wal.back().lastIndex = cmd.index
+		atomic.StoreUint64(&wal.nextIndex, cmd.index+1)
+		wal.written = true
+		wal.unsyncedBytes += int64(len(cmd.data))
+		wal.unsyncedRecords++
+
+		wal.pending = append(wal.pending, *cmd)
+
+		if err := wal.rotateIfNeed(); err != nil {
+			wal.ackPending(err)
+		}
+		return false
+
+	case cmdBatch:
+		if err := wal.writeBatch(cmd.entries); err != nil {
+			cmd.onFailure(err)
+			if wal.failure != nil {
+				wal.ackPending(wal.failure)
+			}
+			return false
+		}
+		wal.written = true
+		wal.pending = append(wal.pending, *cmd)
+
+		if err := wal.rotateIfNeed(); err != nil {
+			wal.ackPending(err)
+		}
+		return false
+
+	case cmdTruncate:
+		if len(wal.pending) > 0 {
+			wal.ackPending(wal.sync())
+		}
+		if wal.failure != nil {
+			cmd.onFailure(wal.failure)
+			return false
+		}
+		err := wal.truncateAfter(cmd.index)
+		if err != nil && !errors.Is(err, ErrIndexOutOfRange) {
+			err = wal.fail(err)
+		}
+		cmd.onResult(err)
+		return false
+
+	case cmdRelease:
+		err := wal.releaseBefore(cmd.index)
+		if err != nil {
+			err = wal.fail(err)
+		}
+		cmd.onResult(err)
+		return false
+
+	case cmdFlush:
+		err := wal.back().file.Flush()
+		if err != nil {
+			err = wal.fail(err)
+		}
+		cmd.onResult(err)
+		return false
+
+	case cmdSync:
+		wal.pending = append(wal.pending, *cmd)
+		return true
+	}
+	return false
+}
+
+// checkRecord test whether the record could be written when the
+// index of next record is next, so invalid records never fail wal.
+func checkRecord(next, index uint64, data []byte) error {
+	if index != next {
+		return fmt.Errorf("%w: write %d, want %d", ErrIndexNotContinuous, index, next)
+	}
+	if len(data) == 0 {
+		return record.ErrEmptyRecord
+	}
+	return nil
+}
+
+// writeBatch write records of batch and the commit of batch,
+// the file is rotated between records if it's full, so a batch
+// may crosses files.
+func (wal *Wal) writeBatch(entries []Entry) error {
+	next := wal.back().lastIndex + 1
+	for i := range entries {
+		if err := checkRecord(next+uint64(i), entries[i].Index, entries[i].Data); err != nil {
+			return err
+		}
+	}
+
+	for i := range entries {
+		if err := wal.back().file.WriteBatch(entries[i].Index, entries[i].Data); err != nil {
+			return wal.fail(err)
+		}
+		wal.back().lastIndex = entries[i].Index
+		wal.unsyncedBytes += int64(len(entries[i].Data))
+		wal.unsyncedRecords++
+
+		if err := wal.rotateIfNeed(); err != nil {
+			wal.ackPending(err)
+			return err
+		}
+	}
+
+	last := entries[len(entries)-1].Index
+	if err := wal.back().file.WriteCommit(last, len(entries)); err != nil {
+		return wal.fail(err)
+	}
+	wal.back().lastIndex = last
+	atomic.StoreUint64(&wal.nextIndex, last+1)
+	return nil
+}
+
+// commit sync the records written by batch if required by
+// caller or sync policy, and acknowledge the waiters.
+func (wal *Wal) commit(needSync bool) {
+	if len(wal.pending) == 0 {
+		return
+	}
+
+	policy := &wal.opts.SyncPolicy
+	if needSync || policy.shouldSync(wal.unsyncedBytes, wal.unsyncedRecords) {
+		wal.ackPending(wal.sync())
+	} else if policy.Mode == SyncManual {
+		wal.ackPending(nil)
+	}
+}
+
+// publish make the records written by batch visible to tailing
+// readers, and notify them.
+func (wal *Wal) publish() {
+	if !wal.written {
+		return
+	}
+	wal.written = false
+	if atomic.LoadInt32(&wal.tailers) == 0 {
+		return
+	}
+	if err := wal.back().file.Flush(); err != nil {
+		wal.fail(err)
+		return
+	}
+	wal.notify()
+}
+
+// notify wake up all tailing readers.
+func (wal *Wal) notify() {
+	wal.notifyMu.Lock()
+	close(wal.notifyCh)
+	wal.notifyCh = make(chan struct{})
+	wal.notifyMu.Unlock()
+}
+
+// changed returns a channel closed when new records are visible.
+func (wal *Wal) changed() <-chan struct{} {
+	wal.notifyMu.Lock()
+	defer wal.notifyMu.Unlock()
+	return wal.notifyCh
+}
+
+// sync sync the records of last file, wal is failed if it fails,
+// since the records may be lost even if the next sync succeeds.
+func (wal *Wal) sync() error {
+	if wal.failure != nil {
+		return wal.failure
+	}
+	wal.syncs++
+	if err := wal.back().file.Sync(); err != nil {
+		return wal.fail(err)
+	}
+	wal.unsyncedBytes = 0
+	wal.unsyncedRecords = 0
+	// the records of batch written by now aren't durable until
+	// the commit of batch is synced, such as rotated in the middle.
+	wal.setSynced(atomic.LoadUint64(&wal.nextIndex) - 1)
+	return nil
+}
+
+// setSynced update the last synced index, and notify waiters.
+func (wal *Wal) setSynced(index uint64) {
+	if atomic.LoadUint64(&wal.syncedNext) == index+1 {
+		return
+	}
+	atomic.StoreUint64(&wal.syncedNext, index+1)
+
+	wal.notifyMu.Lock()
+	close(wal.durableCh)
+	wal.durableCh = make(chan struct{})
+	wal.notifyMu.Unlock()
+}
+
+// durableChanged returns a channel closed when records are synced.
+func (wal *Wal) durableChanged() <-chan struct{} {
+	wal.notifyMu.Lock()
+	defer wal.notifyMu.Unlock()
+	return wal.durableCh
+}
+
+// ackPending resolve all commands wait for sync.
+func (wal *Wal) ackPending(err error) {
+	for i := range wal.pending {
+		wal.pending[i].onResult(err)
+	}
+	wal.pending = wal.pending[:0]
 }
 
 func (wal *Wal) rotateIfNeed() error {
 	rf := wal.back()
 	if !rf.file.Full() {
+		// the next file is preallocated once the last one is half
+		// full, so it isn't allocated if wal never rotates.
+		if rf.file.Offset() >= wal.opts.SegmentSize/2 {
+			wal.allocator.prepare()
+		}
 		return nil
 	}
 
-	if err := rf.file.Sync(); err != nil {
+	if err := wal.sync(); err != nil {
 		return err
 	}
+	// all pending appends are in the synced file.
+	wal.ackPending(nil)
+
+	wal.allocator.seal(rf)
 
-	nrf, err := createFile(wal.walDir, rf.seq+1, rf.lastIndex+1)
+	nrf, err := wal.nextFile()
 	if err != nil {
-		return err
+		return wal.fail(err)
 	}
 
+	wal.mu.Lock()
 	wal.recordFiles = append(wal.recordFiles, nrf)
+	wal.mu.Unlock()
 	return nil
 }
 
+// releaseBefore removes the files whose records are all below index,
+// from the oldest one, so the sequences of rest files are always
+// continuous even if crashed in the middle.
+func (wal *Wal) releaseBefore(index uint64) error {
+	var err error
+	released := 0
+	if len(wal.recordFiles) > 1 && wal.recordFiles[1].index <= index {
+		wal.allocator.wait()
+	}
+	for len(wal.recordFiles) > 1 && wal.recordFiles[1].index <= index {
+		rf := wal.recordFiles[0]
+		if err = rf.file.Close(); err != nil {
+			break
+		}
+		wal.mu.Lock()
+		wal.recordFiles = wal.recordFiles[1:]
+		wal.mu.Unlock()
+		released++
+		if err = record.RemoveFile(rf.filename); err != nil {
+			break
+		}
+		wal.opts.Logger.Debugf("release wal file %s", rf.filename)
+	}
+
+	if released > 0 {
+		if serr := file.SyncDir(wal.walDir); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
 func (wal *Wal) back() *recordFile {
 	return wal.recordFiles[len(wal.recordFiles)-1]
 }
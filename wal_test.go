@@ -2,12 +2,14 @@ package wal
 
 import (
 	"bytes"
+	"errors"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/thinkermao/wal-go/file"
+	"github.com/thinkermao/wal-go/record"
 )
 
 func emptyConsumer(index uint64, data []byte) error {
@@ -22,8 +24,8 @@ func createTmpDir(t *testing.T) string {
 	return path
 }
 
-func createFileAndClose(t *testing.T, path string) {
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0766)
+func createFileAndClose(t *testing.T, path string, seq, idx uint64) {
+	f, err := record.CreateFile(path, seq, idx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -63,7 +65,7 @@ func TestOpenAtIndex(t *testing.T) {
 		// idx great
 		{2, 10, 11, nil},
 		// idx less
-		{0, 5, 1, errFileNotFound},
+		{0, 5, 1, ErrFileNotFound},
 	}
 
 	for i, test := range tests {
@@ -73,10 +75,10 @@ func TestOpenAtIndex(t *testing.T) {
 
 			filename := walName(test.seq, test.idx)
 			path := filepath.Join(dir, filename)
-			createFileAndClose(t, path)
+			createFileAndClose(t, path, test.seq, test.idx)
 
 			w, err := Open(dir, test.at, emptyConsumer)
-			if err != test.werr {
+			if !errors.Is(err, test.werr) {
 				t.Fatalf("want: %v, get: %v", test.werr, err)
 			}
 
@@ -92,6 +94,89 @@ func TestOpenAtIndex(t *testing.T) {
 	}
 }
 
+func TestOpenBadHeader(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	// renamed file
+	path := filepath.Join(dir, walName(0, 0))
+	createFileAndClose(t, path, 0, 0)
+	if err := os.Rename(path, filepath.Join(dir, walName(0, 5))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir, 5, emptyConsumer); !errors.Is(err, ErrHeaderMismatch) {
+		t.Errorf("want: %v, get: %v", ErrHeaderMismatch, err)
+	}
+	os.RemoveAll(dir)
+
+	// foreign file
+	dir = createTmpDir(t)
+	f, err := os.Create(filepath.Join(dir, walName(0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, 1024))
+	f.Close()
+	if _, err := Open(dir, 0, emptyConsumer); err == nil {
+		t.Errorf("open foreign file must failed")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	opts := &Options{SegmentSize: 1024 * 1024}
+	wal, err := CreateWithOptions(dir, 1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := make([]byte, 64*1024)
+	var idx uint64
+	for idx = 1; idx <= 40; idx++ {
+		if err = <-wal.Write(idx, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := readAllWalNames(dir, DefaultOptions().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 {
+		t.Fatalf("len(names) = %d, want 3", len(names))
+	}
+	for i, name := range names {
+		seq, first := mustParseWalName(name)
+		if seq != uint64(i) {
+			t.Errorf("#%d: seq = %d, want %d", i, seq, i)
+		}
+		if i > 0 && first == 1 {
+			t.Errorf("#%d: first index of rotated file must not be 1", i)
+		}
+	}
+
+	count := 0
+	wal, err = OpenWithOptions(dir, 1, func(index uint64, data []byte) error {
+		count++
+		if index != uint64(count) {
+			t.Fatalf("index = %d, want %d", index, count)
+		}
+		return nil
+	}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(count) != idx-1 {
+		t.Errorf("count = %d, want %d", count, idx-1)
+	}
+	wal.Close()
+}
+
 func randRecord() []byte {
 	length := rand.Intn(1024 * 10)
 	bytes := make([]byte, length)
@@ -250,3 +335,99 @@ func TestAppend(t *testing.T) {
 	}
 
 }
+
+// createFileWithRecords create wal file with records in [idx, idx+n),
+// and returns the offsets of records.
+func createFileWithRecords(t *testing.T, dir string, seq, idx uint64, n int) []int64 {
+	f, err := record.CreateFile(filepath.Join(dir, walName(seq, idx)), seq, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offsets := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		offsets = append(offsets, int64(f.Offset()))
+		if err = f.Write(idx+uint64(i), []byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return offsets
+}
+
+func corruptFile(t *testing.T, path string, offset int64) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err = f.WriteAt([]byte{0xff, 0xff}, offset+4); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenTornTail(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	createFileWithRecords(t, dir, 0, 0, 10)
+	offsets := createFileWithRecords(t, dir, 1, 10, 10)
+	corruptFile(t, filepath.Join(dir, walName(1, 10)), offsets[9])
+
+	var last uint64
+	wal, err := Open(dir, 0, func(index uint64, data []byte) error {
+		last = index
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 18 {
+		t.Errorf("last = %d, want 18", last)
+	}
+
+	if err = <-wal.Write(19, []byte{0x1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	wal, err = Open(dir, 0, func(index uint64, data []byte) error {
+		if index != uint64(count) {
+			t.Fatalf("index = %d, want %d", index, count)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 20 {
+		t.Errorf("count = %d, want 20", count)
+	}
+	wal.Close()
+}
+
+func TestOpenCorruptedSealed(t *testing.T) {
+	dir := createTmpDir(t)
+	defer os.RemoveAll(dir)
+
+	offsets := createFileWithRecords(t, dir, 0, 0, 10)
+	createFileWithRecords(t, dir, 1, 10, 10)
+	corruptFile(t, filepath.Join(dir, walName(0, 0)), offsets[9])
+
+	_, err := Open(dir, 0, emptyConsumer)
+	if !record.IsCorrupted(err) {
+		t.Errorf("want corrupted, get: %v", err)
+	}
+	var cerr *record.CorruptionError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("err = %v, want CorruptionError", err)
+	}
+	if cerr.File != filepath.Join(dir, walName(0, 0)) || cerr.Offset != offsets[9] || cerr.Index != 9 {
+		t.Errorf("err = %v, want index 9 at offset %d", err, offsets[9])
+	}
+}
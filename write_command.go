@@ -1,10 +1,16 @@
 package wal
 
+import "context"
+
 type cmdType int
 
 const (
 	cmdSync cmdType = iota
 	cmdAppend
+	cmdBatch
+	cmdTruncate
+	cmdRelease
+	cmdFlush
 )
 
 type command struct {
@@ -12,6 +18,8 @@ type command struct {
 	result  chan error
 	index   uint64
 	data    []byte
+	entries []Entry
+	ctx     context.Context // nil if command couldn't be cancelled
 }
 
 func genSync() (command, <-chan error) {
@@ -32,6 +40,49 @@ func genAppend(index uint64, bytes []byte) (command, <-chan error) {
 	}, result
 }
 
+func genBatch(entries []Entry) (command, <-chan error) {
+	result := make(chan error, 1)
+	return command{
+		cmdType: cmdBatch,
+		entries: entries,
+		result:  result,
+	}, result
+}
+
+func genTruncate(index uint64) (command, <-chan error) {
+	result := make(chan error, 1)
+	return command{
+		cmdType: cmdTruncate,
+		index:   index,
+		result:  result,
+	}, result
+}
+
+func genRelease(index uint64) (command, <-chan error) {
+	result := make(chan error, 1)
+	return command{
+		cmdType: cmdRelease,
+		index:   index,
+		result:  result,
+	}, result
+}
+
+func genFlush() (command, <-chan error) {
+	result := make(chan error, 1)
+	return command{
+		cmdType: cmdFlush,
+		result:  result,
+	}, result
+}
+
+// cancelled returns error if command is cancelled by caller.
+func (wc *command) cancelled() error {
+	if wc.ctx == nil {
+		return nil
+	}
+	return wc.ctx.Err()
+}
+
 func (wc *command) onSuccess() {
 	wc.result <- nil
 	close(wc.result)
@@ -41,3 +92,11 @@ func (wc *command) onFailure(err error) {
 	wc.result <- err
 	close(wc.result)
 }
+
+func (wc *command) onResult(err error) {
+	if err != nil {
+		wc.onFailure(err)
+	} else {
+		wc.onSuccess()
+	}
+}